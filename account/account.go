@@ -9,8 +9,11 @@ package account
 */
 import "C"
 import (
+	"bytes"
+	"encoding/hex"
 	"errors"
 	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -100,6 +103,9 @@ func credentialAddressPtr(a *credential.Address) *C.self_credential_address
 //go:linkname newCredentialGraph github.com/joinself/self-go-sdk/credential.newCredentialGraph
 func newCredentialGraph(ptr *C.self_credential_graph) *credential.Graph
 
+//go:linkname credentialGraphWithSources github.com/joinself/self-go-sdk/credential.credentialGraphWithSources
+func credentialGraphWithSources(g *credential.Graph, registry *credential.TrustedIssuerRegistry, presentations []*credential.VerifiablePresentation)
+
 //go:linkname revocationStatementPtr github.com/joinself/self-go-sdk/revocation.statementPtr
 func revocationStatementPtr(s *revocation.Statement) *C.self_revocation_statement
 
@@ -163,6 +169,9 @@ func fromSigningPublicKeyCollection(ptr *C.self_collection_signing_public_key) [
 //go:linkname fromCredentialExchangeCollection github.com/joinself/self-go-sdk/credential.fromCredentialExchangeCollection
 func fromCredentialExchangeCollection(ptr *C.self_collection_credential_exchange) []*credential.Exchange
 
+//go:linkname exchangeWithLicense github.com/joinself/self-go-sdk/credential.exchangeWithLicense
+func exchangeWithLicense(e *credential.Exchange, license *credential.License)
+
 //go:linkname fromVerifiableCredentialCollection github.com/joinself/self-go-sdk/credential.fromVerifiableCredentialCollection
 func fromVerifiableCredentialCollection(ptr *C.self_collection_verifiable_credential) []*credential.VerifiableCredential
 
@@ -187,6 +196,12 @@ type Account struct {
 	callbacks *Callbacks
 	config    *Config
 	status    int32
+	receipts  sync.Mutex
+	objects   sync.Mutex
+	exchanges sync.Mutex
+	done      chan struct{}
+	workers   sync.WaitGroup
+	lifecycle sync.Mutex
 }
 
 // New creates a new self account
@@ -259,6 +274,10 @@ func New(cfg *Config) (*Account, error) {
 		}
 	}
 
+	account.done = make(chan struct{})
+	account.objectCompaction()
+	account.credentialExpiryWatch()
+
 	return account, nil
 }
 
@@ -342,6 +361,14 @@ func (a *Account) Configure(cfg *Config) error {
 		}
 	}
 
+	a.lifecycle.Lock()
+	a.done = make(chan struct{})
+	a.lifecycle.Unlock()
+
+	a.objectIndexRebuild()
+	a.objectCompaction()
+	a.credentialExpiryWatch()
+
 	return nil
 }
 
@@ -590,7 +617,10 @@ func (a *Account) CredentialGraphCreate(registry *credential.TrustedIssuerRegist
 		return nil, status.New(result)
 	}
 
-	return newCredentialGraph(credentialGraph), nil
+	graph := newCredentialGraph(credentialGraph)
+	credentialGraphWithSources(graph, registry, presentations)
+
+	return graph, nil
 }
 
 // CredentialGraphValidFor validates and filters credentials from a given collection of verifiable presentations, validating credentials, presentations and ensuring that an issuer, subject or holder keys have not been revoked and were valid at the time of use.
@@ -640,6 +670,37 @@ func (a *Account) CredentialStore(verifiedCredential *credential.VerifiableCrede
 	return nil
 }
 
+// CredentialDelete deletes a stored credential by it's hash. Records of the credential being
+// exchanged and any locally stored objects it references that are no longer referenced by
+// other credentials or presentations are also deleted. A tombstone of the deletion is kept
+func (a *Account) CredentialDelete(credentialHash []byte) error {
+	credentials, err := a.CredentialLookupByCredentialHash(credentialHash)
+	if err != nil {
+		return err
+	}
+
+	if len(credentials) == 0 {
+		return errors.New("credential not found")
+	}
+
+	hashPtr := C.CBytes(credentialHash)
+	hashLen := len(credentialHash)
+
+	result := C.self_account_credential_remove(
+		a.account,
+		(*C.uint8_t)(hashPtr),
+		C.size_t(hashLen),
+	)
+
+	C.free(hashPtr)
+
+	if result > 0 {
+		return status.New(result)
+	}
+
+	return a.credentialDeleted(credentials[0])
+}
+
 // CredentialLookup looks up all credentials stored to the account
 func (a *Account) CredentialLookup() ([]*credential.VerifiableCredential, error) {
 	var collection *C.self_collection_verifiable_credential
@@ -833,8 +894,16 @@ func (a *Account) CredentialSharedWithAddressByCredentialType(withAddress *signi
 	return credentials, nil
 }
 
-// CredentialExchangeTrack tracks an credential exchange with a given addresss
+// CredentialExchangeTrack tracks an credential exchange with a given addresss, under an optional license
 func (a *Account) CredentialExchangeTrack(withAddress *signing.PublicKey, credential *credential.VerifiableCredential, underLicense *credential.License) error {
+	credentialHash, err := credential.CredentialHash()
+	if err != nil {
+		return err
+	}
+
+	a.exchanges.Lock()
+	defer a.exchanges.Unlock()
+
 	result := C.self_account_credential_exchange_track(
 		a.account,
 		signingPublicKeyPtr(withAddress),
@@ -846,7 +915,7 @@ func (a *Account) CredentialExchangeTrack(withAddress *signing.PublicKey, creden
 		return status.New(result)
 	}
 
-	return nil
+	return a.exchangeLicenseStore(withAddress, credentialHash, underLicense)
 }
 
 // CredentialExchangeLog returns a log of credentials shared
@@ -870,7 +939,7 @@ func (a *Account) CredentialExchangeLog() ([]*credential.Exchange, error) {
 		collection,
 	)
 
-	return credentials, nil
+	return credentials, a.exchangeLicenses(credentials)
 }
 
 // CredentialExchangeLogWithAddress returns a log of credentials shared with an address
@@ -895,7 +964,7 @@ func (a *Account) CredentialExchangeLogWithAddress(withAddress *signing.PublicKe
 		collection,
 	)
 
-	return credentials, nil
+	return credentials, a.exchangeLicenses(credentials)
 }
 
 // CredentialExchangeLogCredential returns a log of every exchange of a given credential
@@ -920,7 +989,111 @@ func (a *Account) CredentialExchangeLogCredential(verifiableCredential *credenti
 		collection,
 	)
 
-	return credentials, nil
+	return credentials, a.exchangeLicenses(credentials)
+}
+
+// CredentialExchangeForget deletes the records of credentials exchanged with an address. If credential
+// hashes are provided, only records of exchanging those credentials are deleted. A tombstone
+// of each deletion is kept
+func (a *Account) CredentialExchangeForget(withAddress *signing.PublicKey, credentialHash ...[]byte) error {
+	exchanges, err := a.CredentialExchangeLogWithAddress(withAddress)
+	if err != nil {
+		return err
+	}
+
+	return a.exchangesForget(exchanges, func(exchanged *credential.Exchange) bool {
+		return len(credentialHash) == 0 || slices.ContainsFunc(credentialHash, func(h []byte) bool {
+			return bytes.Equal(h, exchanged.CredentialHash())
+		})
+	})
+}
+
+// CredentialExchangeForgetCredential deletes the records of a credential being exchanged with any address.
+// A tombstone of each deletion is kept
+func (a *Account) CredentialExchangeForgetCredential(credentialHash []byte) error {
+	exchanges, err := a.CredentialExchangeLog()
+	if err != nil {
+		return err
+	}
+
+	return a.exchangesForget(exchanges, func(exchanged *credential.Exchange) bool {
+		return bytes.Equal(exchanged.CredentialHash(), credentialHash)
+	})
+}
+
+// CredentialExchangeForgetHolder deletes the records of exchanging credentials whose subject is the holder,
+// with any address. Exchanges of credentials that are no longer stored are kept. A tombstone of each deletion is kept
+func (a *Account) CredentialExchangeForgetHolder(holder *signing.PublicKey) error {
+	exchanges, err := a.CredentialExchangeLog()
+	if err != nil {
+		return err
+	}
+
+	held := make(map[string]bool)
+
+	for _, exchanged := range exchanges {
+		credentialHash := hex.EncodeToString(exchanged.CredentialHash())
+
+		if _, checked := held[credentialHash]; checked {
+			continue
+		}
+
+		credentials, err := a.CredentialLookupByCredentialHash(exchanged.CredentialHash())
+		if err != nil {
+			return err
+		}
+
+		held[credentialHash] = len(credentials) > 0 && credentials[0].CredentialSubject().Address().Matches(holder)
+	}
+
+	return a.exchangesForget(exchanges, func(exchanged *credential.Exchange) bool {
+		return held[hex.EncodeToString(exchanged.CredentialHash())]
+	})
+}
+
+// exchangesForget deletes the records of the exchanges matching the filter
+func (a *Account) exchangesForget(exchanges []*credential.Exchange, filter func(exchanged *credential.Exchange) bool) error {
+	forgotten := make(map[string]bool)
+
+	for _, exchanged := range exchanges {
+		if !filter(exchanged) {
+			continue
+		}
+
+		withAddress := exchanged.WithAddress()
+		exchangedHash := exchanged.CredentialHash()
+
+		key := withAddress.String() + "/" + hex.EncodeToString(exchangedHash)
+
+		if forgotten[key] {
+			continue
+		}
+
+		hashPtr := C.CBytes(exchangedHash)
+		hashLen := len(exchangedHash)
+
+		result := C.self_account_credential_exchange_remove(
+			a.account,
+			signingPublicKeyPtr(withAddress),
+			(*C.uint8_t)(hashPtr),
+			C.size_t(hashLen),
+		)
+
+		C.free(hashPtr)
+
+		if result > 0 {
+			return status.New(result)
+		}
+
+		forgotten[key] = true
+
+		err := a.exchangeForgotten(withAddress, exchangedHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // PresentationIssue signs and issues a verifiable presentation
@@ -965,9 +1138,48 @@ func (a *Account) PresentationStore(verifiedPresentation *credential.VerifiableP
 		return status.New(result)
 	}
 
+	return a.presentationTypesRecord(verifiedPresentation)
+}
+
+// PresentationDelete deletes stored presentations intended for a holder. If presentation types are
+// provided, only presentations of those types are deleted. Any locally stored objects referenced
+// by the presentations that are no longer referenced are also deleted. A tombstone of each
+// deletion is kept
+func (a *Account) PresentationDelete(holder *signing.PublicKey, presentationType ...string) error {
+	presentations, err := a.PresentationLookupByHolder(holder)
+	if err != nil {
+		return err
+	}
+
+	for _, presentation := range presentations {
+		if len(presentationType) > 0 && !slices.ContainsFunc(presentation.PresentationType(), func(t string) bool {
+			return slices.Contains(presentationType, t)
+		}) {
+			continue
+		}
+
+		err = a.presentationRemove(holder, presentation)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+func (a *Account) presentationRemove(holder *signing.PublicKey, presentation *credential.VerifiablePresentation) error {
+	result := C.self_account_presentation_remove(
+		a.account,
+		verifiablePresentationPtr(presentation),
+	)
+
+	if result > 0 {
+		return status.New(result)
+	}
+
+	return a.presentationDeleted(holder, presentation)
+}
+
 // PresentationLookupByHolder looks up presentations inteded for a specific holder
 func (a *Account) PresentationLookupByHolder(holder *signing.PublicKey) ([]*credential.VerifiablePresentation, error) {
 	var collection *C.self_collection_verifiable_presentation
@@ -1420,6 +1632,10 @@ func (a *Account) ObjectUpload(obj *object.Object, persistLocally bool) error {
 		return status.New(result)
 	}
 
+	if persistLocally {
+		return a.objectIndexStore(obj)
+	}
+
 	return nil
 }
 
@@ -1448,7 +1664,7 @@ func (a *Account) ObjectStore(obj *object.Object) error {
 		return status.New(result)
 	}
 
-	return nil
+	return a.objectIndexStore(obj)
 }
 
 // ObjectRetrieve downloads and decrypts an object
@@ -1469,7 +1685,29 @@ func (a *Account) ObjectRetrieve(hash []byte) (*object.Object, error) {
 		return nil, status.New(result)
 	}
 
-	return newObject(objPtr), nil
+	obj := newObject(objPtr)
+
+	a.objectIndexAccess(obj)
+
+	return obj, nil
+}
+
+// ObjectDelete deletes an object from local storage
+func (a *Account) ObjectDelete(hash []byte) error {
+	hashPtr := C.CBytes(hash)
+
+	result := C.self_account_object_remove(
+		a.account,
+		(*C.uint8_t)(hashPtr),
+	)
+
+	C.free(hashPtr)
+
+	if result > 0 {
+		return status.New(result)
+	}
+
+	return a.objectIndexRemove(hash)
 }
 
 // ConnectionNegotiate negotiates a new encrypted group connection with an address. sends a key
@@ -1624,8 +1862,17 @@ func (a *Account) ConnectionPairwiseStore(asAddress *credential.Address, withIde
 
 // MessageSend sends a message to an address that we have established an encrypted group with
 // the OnAcknowledgement and OnError callback will be invoked upon receiving the servers response,
-// referencing the id of the messages content
+// referencing the id of the messages content. The delivery status of the message can be queried
+// with MessageStatus
 func (a *Account) MessageSend(toAddress *signing.PublicKey, content *message.Content) error {
+	if content.ContentType() != message.ContentTypeReceipt {
+		// record the recipient before sending, as their receipt may arrive before the send returns
+		err := receiptRecipientStore(a, content.ID(), toAddress)
+		if err != nil {
+			return err
+		}
+	}
+
 	result := C.self_account_message_send(
 		a.account,
 		signingPublicKeyPtr(toAddress),
@@ -1636,6 +1883,10 @@ func (a *Account) MessageSend(toAddress *signing.PublicKey, content *message.Con
 		return status.New(result)
 	}
 
+	if content.ContentType() != message.ContentTypeReceipt {
+		receiptUpdate(a, content.ID(), message.DeliveryStatusSent)
+	}
+
 	return nil
 }
 
@@ -1654,8 +1905,44 @@ func (a *Account) NotificationSend(toAddress *signing.PublicKey, summary *messag
 	return nil
 }
 
-// Close shuts down the account
+// MessageSendWithNotification sends a message and a push notification for the message
+// to the recipient. An optional summary, such as one created by a message.NotificationComposer,
+// can be provided, otherwise the default summary of the content is used
+func (a *Account) MessageSendWithNotification(toAddress *signing.PublicKey, content *message.Content, summary ...*message.ContentSummary) error {
+	var notification *message.ContentSummary
+
+	if len(summary) > 0 && summary[0] != nil {
+		notification = summary[0]
+	} else {
+		defaultSummary, err := content.Summary()
+		if err != nil {
+			return err
+		}
+
+		notification = defaultSummary
+	}
+
+	err := a.MessageSend(toAddress, content)
+	if err != nil {
+		return err
+	}
+
+	return a.NotificationSend(toAddress, notification)
+}
+
+// Close shuts down the account, waiting for any background tasks to stop before the account is destroyed
 func (a *Account) Close() error {
+	a.lifecycle.Lock()
+
+	if a.done != nil {
+		close(a.done)
+		a.done = nil
+	}
+
+	a.lifecycle.Unlock()
+
+	a.workers.Wait()
+
 	result := C.self_account_destroy(
 		a.account,
 	)
@@ -1667,6 +1954,31 @@ func (a *Account) Close() error {
 	return nil
 }
 
+// background runs a task until the account is closed, which waits for the task to return
+// before the account is destroyed. Returns false if the account has already been closed
+func (a *Account) background(task func(done chan struct{})) bool {
+	a.lifecycle.Lock()
+	defer a.lifecycle.Unlock()
+
+	if a.done == nil {
+		return false
+	}
+
+	a.workers.Add(1)
+
+	go func(done chan struct{}) {
+		defer a.workers.Done()
+		task(done)
+	}(a.done)
+
+	return true
+}
+
+// runs a task in the background of the account. used by packages that run their own tasks, so not exported
+func accountBackground(a *Account, task func(done chan struct{})) bool {
+	return a.background(task)
+}
+
 // issues a push token. mobile specific so not exported
 func tokenIssuePush(a *Account, forAddress *signing.PublicKey, providerAddress *exchange.PublicKey, pushCredential *platform.Push, delegatable bool) (*token.Token, error) {
 	var token *C.self_token
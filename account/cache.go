@@ -0,0 +1,443 @@
+package account
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/object"
+)
+
+const (
+	objectIndexKeyPrefix      = "objects/index/"
+	presentationTypeKeyPrefix = "presentations/types/"
+)
+
+// claims of the built-in credential types that hold the hash of an object
+var defaultReferenceClaims = []string{"imageHash", "sourceImageHash", "targetImageHash"}
+
+// ObjectCachePolicy defines how objects persisted to local storage are managed
+type ObjectCachePolicy struct {
+	// MaxBytes the maximum size of all objects stored locally. zero disables eviction
+	MaxBytes int64
+	// CompactionInterval how often to evict objects that exceed the maximum size. zero disables background compaction
+	CompactionInterval time.Duration
+	// ReferenceClaims the names of credential claims that hold the hex encoded hash of an object.
+	// Objects referenced by these claims in stored credentials and presentations are never evicted.
+	// Defaults to the image hash claims of the built-in credential types
+	ReferenceClaims []string
+}
+
+// ObjectInfo describes an object persisted to local storage
+type ObjectInfo struct {
+	Hash     []byte    `json:"-"`
+	MimeType string    `json:"mimeType"`
+	Size     int64     `json:"size"`
+	Stored   time.Time `json:"stored"`
+	Accessed time.Time `json:"accessed"`
+	Pinned   bool      `json:"pinned"`
+}
+
+// ObjectStats summarises the objects persisted to local storage
+type ObjectStats struct {
+	Objects       int
+	Bytes         int64
+	PinnedObjects int
+	PinnedBytes   int64
+}
+
+// ObjectList lists all objects persisted to local storage. Objects stored before the object index
+// existed are added to the index when the account is configured, if they are referenced by a stored
+// credential or presentation, or otherwise when they are retrieved
+func (a *Account) ObjectList() ([]*ObjectInfo, error) {
+	keys, err := a.ValueKeys(objectIndexKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]*ObjectInfo, 0, len(keys))
+
+	for _, key := range keys {
+		hash, err := hex.DecodeString(strings.TrimPrefix(key, objectIndexKeyPrefix))
+		if err != nil {
+			continue
+		}
+
+		info, err := a.objectIndexLookup(hash)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, info)
+	}
+
+	return objects, nil
+}
+
+// ObjectStats returns the number and size of objects persisted to local storage
+func (a *Account) ObjectStats() (*ObjectStats, error) {
+	pinned, err := a.objectReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	a.objectIndexBackfill(pinned)
+
+	objects, err := a.ObjectList()
+	if err != nil {
+		return nil, err
+	}
+
+	var stats ObjectStats
+
+	for _, info := range objects {
+		stats.Objects++
+		stats.Bytes += info.Size
+
+		if info.Pinned || pinned[hex.EncodeToString(info.Hash)] {
+			stats.PinnedObjects++
+			stats.PinnedBytes += info.Size
+		}
+	}
+
+	return &stats, nil
+}
+
+// ObjectPin pins an object in local storage, preventing it from being evicted
+func (a *Account) ObjectPin(hash []byte) error {
+	return a.objectIndexPin(hash, true)
+}
+
+// ObjectUnpin unpins an object in local storage, allowing it to be evicted
+func (a *Account) ObjectUnpin(hash []byte) error {
+	return a.objectIndexPin(hash, false)
+}
+
+// ObjectCompact evicts the least recently used objects from local storage until the total size
+// of stored objects is within the accounts cache policy. Objects that have been pinned, or are
+// referenced by stored credentials or presentations are never evicted. Returns the number of
+// objects that were evicted
+func (a *Account) ObjectCompact() (int, error) {
+	policy := a.config.ObjectCache
+
+	if policy.MaxBytes < 1 {
+		return 0, nil
+	}
+
+	pinned, err := a.objectReferences()
+	if err != nil {
+		return 0, err
+	}
+
+	a.objectIndexBackfill(pinned)
+
+	objects, err := a.ObjectList()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+
+	for _, info := range objects {
+		total += info.Size
+	}
+
+	if total <= policy.MaxBytes {
+		return 0, nil
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Accessed.Before(objects[j].Accessed)
+	})
+
+	var evicted int
+
+	for _, info := range objects {
+		if total <= policy.MaxBytes {
+			break
+		}
+
+		if info.Pinned || pinned[hex.EncodeToString(info.Hash)] {
+			continue
+		}
+
+		err = a.ObjectDelete(info.Hash)
+		if err != nil {
+			return evicted, err
+		}
+
+		total -= info.Size
+		evicted++
+	}
+
+	return evicted, nil
+}
+
+// objectCompaction periodically compacts local object storage until the account is closed
+func (a *Account) objectCompaction() {
+	interval := a.config.ObjectCache.CompactionInterval
+
+	if interval < 1 || a.config.ObjectCache.MaxBytes < 1 {
+		return
+	}
+
+	a.background(func(done chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				evicted, err := a.ObjectCompact()
+				if err != nil {
+					logger()(LogWarn, fmt.Sprintf("failed to compact object storage. error: %s", err))
+					continue
+				}
+
+				if evicted > 0 {
+					logger()(LogInfo, fmt.Sprintf("evicted %d objects from object storage", evicted))
+				}
+			}
+		}
+	})
+}
+
+// objectReferences collects the hashes of all objects referenced by the reference claims
+// of credentials and presentations stored to the account
+func (a *Account) objectReferences() (map[string]bool, error) {
+	credentials, err := a.CredentialLookup()
+	if err != nil {
+		return nil, err
+	}
+
+	presentationTypes, err := a.presentationTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, presentationType := range presentationTypes {
+		presentations, err := a.PresentationLookupByPresentationType(presentationType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, presentation := range presentations {
+			credentials = append(credentials, presentation.Credentials()...)
+		}
+	}
+
+	references := make(map[string]bool)
+
+	for _, c := range credentials {
+		a.collectObjectReferences(c, references)
+	}
+
+	return references, nil
+}
+
+// collectObjectReferences collects the hashes of objects held by the credential's reference claims
+func (a *Account) collectObjectReferences(c *credential.VerifiableCredential, references map[string]bool) {
+	claims, err := c.CredentialSubjectClaims()
+	if err != nil {
+		return
+	}
+
+	referenceClaims := defaultReferenceClaims
+	if len(a.config.ObjectCache.ReferenceClaims) > 0 {
+		referenceClaims = a.config.ObjectCache.ReferenceClaims
+	}
+
+	collectReferenceClaims(claims, referenceClaims, false, references)
+}
+
+// presentationTypes returns the types of all presentations stored to the account. Presentations
+// stored before their types were recorded are found through the built-in presentation types
+func (a *Account) presentationTypes() ([]string, error) {
+	keys, err := a.ValueKeys(presentationTypeKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	presentationTypes := []string{
+		credential.PresentationTypePassport,
+		credential.PresentationTypeFacialComparison,
+		credential.PresentationTypeLivenessAndFacialComparison,
+		credential.PresentationTypeBiometricAnchor,
+		credential.PresentationTypeSharingAgreement,
+		credential.PresentationTypeProfile,
+		credential.PresentationTypeContactDetails,
+		credential.PresentationTypeApplicationPublisher,
+	}
+
+	for _, key := range keys {
+		presentationType := strings.TrimPrefix(key, presentationTypeKeyPrefix)
+
+		if !slices.Contains(presentationTypes, presentationType) {
+			presentationTypes = append(presentationTypes, presentationType)
+		}
+	}
+
+	return presentationTypes, nil
+}
+
+// presentationTypesRecord records the types of a stored presentation, so the
+// credentials it holds can be found when collecting object references
+func (a *Account) presentationTypesRecord(presentation *credential.VerifiablePresentation) error {
+	for _, presentationType := range presentation.PresentationType() {
+		err := a.ValueStore(presentationTypeKeyPrefix+presentationType, []byte{1})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// objectIndexRebuild indexes objects that were stored before the object index existed. Local
+// storage cannot be listed, so objects are found through the credentials and presentations that
+// reference them
+func (a *Account) objectIndexRebuild() {
+	references, err := a.objectReferences()
+	if err != nil {
+		logger()(LogWarn, fmt.Sprintf("failed to rebuild object index. error: %s", err))
+		return
+	}
+
+	a.objectIndexBackfill(references)
+}
+
+// objectIndexBackfill indexes referenced objects that were stored before the object index existed
+func (a *Account) objectIndexBackfill(references map[string]bool) {
+	for reference := range references {
+		hash, err := hex.DecodeString(reference)
+		if err != nil {
+			continue
+		}
+
+		_, err = a.objectIndexLookup(hash)
+		if err == nil {
+			continue
+		}
+
+		// retrieving the object adds it to the index if it is stored locally
+		_, _ = a.ObjectRetrieve(hash)
+	}
+}
+
+func (a *Account) objectIndexStore(obj *object.Object) error {
+	a.objects.Lock()
+	defer a.objects.Unlock()
+
+	now := time.Now()
+
+	info, err := a.objectIndexLookup(obj.Hash())
+	if err != nil {
+		info = &ObjectInfo{
+			MimeType: obj.MimeType(),
+			Size:     int64(obj.Size()),
+			Stored:   now,
+		}
+	}
+
+	info.Accessed = now
+
+	return a.objectIndexUpdate(obj.Hash(), info)
+}
+
+func (a *Account) objectIndexAccess(obj *object.Object) {
+	a.objects.Lock()
+	defer a.objects.Unlock()
+
+	now := time.Now()
+
+	info, err := a.objectIndexLookup(obj.Hash())
+	if err != nil {
+		// the object was stored before the object index existed
+		info = &ObjectInfo{
+			MimeType: obj.MimeType(),
+			Size:     int64(obj.Size()),
+			Stored:   now,
+		}
+	}
+
+	info.Accessed = now
+
+	err = a.objectIndexUpdate(obj.Hash(), info)
+	if err != nil {
+		logger()(LogWarn, fmt.Sprintf("failed to update object index. error: %s", err))
+	}
+}
+
+func (a *Account) objectIndexPin(hash []byte, pinned bool) error {
+	a.objects.Lock()
+	defer a.objects.Unlock()
+
+	info, err := a.objectIndexLookup(hash)
+	if err != nil {
+		return err
+	}
+
+	info.Pinned = pinned
+
+	return a.objectIndexUpdate(hash, info)
+}
+
+func (a *Account) objectIndexRemove(hash []byte) error {
+	a.objects.Lock()
+	defer a.objects.Unlock()
+
+	return a.ValueRemove(objectIndexKeyPrefix + hex.EncodeToString(hash))
+}
+
+func (a *Account) objectIndexLookup(hash []byte) (*ObjectInfo, error) {
+	encodedInfo, err := a.ValueLookup(objectIndexKeyPrefix + hex.EncodeToString(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	info := ObjectInfo{
+		Hash: hash,
+	}
+
+	return &info, json.Unmarshal(encodedInfo, &info)
+}
+
+func (a *Account) objectIndexUpdate(hash []byte, info *ObjectInfo) error {
+	encodedInfo, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	return a.ValueStore(objectIndexKeyPrefix+hex.EncodeToString(hash), encodedInfo)
+}
+
+// collectReferenceClaims walks a credentials claims, collecting the hex encoded
+// object hashes held by any of the reference claims
+func collectReferenceClaims(claims interface{}, referenceClaims []string, reference bool, references map[string]bool) {
+	switch value := claims.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			collectReferenceClaims(v, referenceClaims, slices.Contains(referenceClaims, k), references)
+		}
+	case []interface{}:
+		for _, v := range value {
+			collectReferenceClaims(v, referenceClaims, reference, references)
+		}
+	case string:
+		if !reference {
+			return
+		}
+
+		hash, err := hex.DecodeString(value)
+		if err == nil && len(hash) == 32 {
+			references[hex.EncodeToString(hash)] = true
+		}
+	}
+}
@@ -343,9 +343,12 @@ func goOnDisconnect(user_data unsafe.Pointer, reason C.self_status) {
 //export goOnAcknowledgement
 func goOnAcknowledgement(user_data unsafe.Pointer, reference *C.cself_reference_t) {
 	account := (*Account)(user_data)
+	ref := newReference(reference)
+
+	receiptUpdate(account, ref.ID(), message.DeliveryStatusRelayed)
 
 	if account.callbacks.OnAcknowledgement != nil {
-		account.callbacks.OnAcknowledgement(account, newReference(reference))
+		account.callbacks.OnAcknowledgement(account, ref)
 	}
 }
 
@@ -361,10 +364,20 @@ func goOnError(user_data unsafe.Pointer, reference *C.cself_reference_t, reason
 //export goOnMessage
 func goOnMessage(user_data unsafe.Pointer, msg *C.cself_message_t) {
 	account := (*Account)(user_data)
+	m := newMessage(msg)
+
+	if event.ContentTypeOf(m) == message.ContentTypeReceipt {
+		receiptHandle(account, m)
+		return
+	}
+
+	if !account.config.SkipDeliveryReceipts {
+		receiptDeliver(account, m)
+	}
 
 	account.callbacks.OnMessage(
 		account,
-		newMessage(msg),
+		m,
 	)
 }
 
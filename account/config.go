@@ -11,7 +11,9 @@ import "C"
 import (
 	"fmt"
 
+	"github.com/joinself/self-go-sdk/credential"
 	"github.com/joinself/self-go-sdk/event"
+	"github.com/joinself/self-go-sdk/message"
 	"github.com/joinself/self-go-sdk/platform"
 )
 
@@ -64,28 +66,33 @@ type Target struct {
 
 // Config stores config for an account
 type Config struct {
-	SkipReady   bool
-	SkipSetup   bool
-	StorageKey  []byte
-	StoragePath string
-	Environment *Target
-	LogLevel    LogLevel
-	Callbacks   Callbacks
+	SkipReady            bool
+	SkipSetup            bool
+	SkipDeliveryReceipts bool
+	StorageKey           []byte
+	StoragePath          string
+	Environment          *Target
+	LogLevel             LogLevel
+	ObjectCache          ObjectCachePolicy
+	CredentialExpiry     CredentialExpiryPolicy
+	Callbacks            Callbacks
 }
 
 // Callbacks defines callbacks invoked by the account
 type Callbacks struct {
-	OnConnect         func(account *Account)
-	OnDisconnect      func(account *Account, err error)
-	OnAcknowledgement func(account *Account, reference *event.Reference)
-	OnError           func(account *Account, reference *event.Reference, err error)
-	OnMessage         func(account *Account, message *event.Message)
-	OnCommit          func(account *Account, commit *event.Commit)
-	OnKeyPackage      func(account *Account, keyPackage *event.KeyPackage)
-	OnProposal        func(account *Account, proposal *event.Proposal)
-	OnWelcome         func(account *Account, welcome *event.Welcome)
-	OnDropped         func(account *Account, dropped *event.Dropped)
-	onIntegrity       func(account *Account, requestHash []byte) *platform.Attestation
+	OnConnect            func(account *Account)
+	OnDisconnect         func(account *Account, err error)
+	OnAcknowledgement    func(account *Account, reference *event.Reference)
+	OnError              func(account *Account, reference *event.Reference, err error)
+	OnMessage            func(account *Account, message *event.Message)
+	OnCommit             func(account *Account, commit *event.Commit)
+	OnKeyPackage         func(account *Account, keyPackage *event.KeyPackage)
+	OnProposal           func(account *Account, proposal *event.Proposal)
+	OnWelcome            func(account *Account, welcome *event.Welcome)
+	OnDropped            func(account *Account, dropped *event.Dropped)
+	OnReceipt            func(account *Account, msg *event.Message, receipt *message.Receipt)
+	OnCredentialExpiring func(account *Account, credential *credential.VerifiableCredential)
+	onIntegrity          func(account *Account, requestHash []byte) *platform.Attestation
 }
 
 func (c *Config) defaults() {
@@ -0,0 +1,246 @@
+package account
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/keypair/signing"
+)
+
+const tombstoneKeyPrefix = "tombstones/"
+
+const (
+	TombstoneCredential   TombstoneKind = "credential"
+	TombstonePresentation TombstoneKind = "presentation"
+	TombstoneExchange     TombstoneKind = "exchange"
+)
+
+// TombstoneKind the kind of record that was deleted
+type TombstoneKind string
+
+// Tombstone a minimal record of deleted data, kept for audit. Tombstones
+// never contain the claims of deleted credentials
+type Tombstone struct {
+	Kind TombstoneKind `json:"kind"`
+	// CredentialHash the hash of the deleted or exchanged credential
+	CredentialHash []byte `json:"credentialHash,omitempty"`
+	// Type the credential or presentation type of the deleted record
+	Type []string `json:"type,omitempty"`
+	// Address the holder of a deleted presentation, or the address a forgotten exchange was with
+	Address string `json:"address,omitempty"`
+	// Objects the hashes of locally stored objects deleted along with the record
+	Objects [][]byte  `json:"objects,omitempty"`
+	Deleted time.Time `json:"deleted"`
+}
+
+// Tombstones returns the tombstones of all deleted records, oldest first
+func (a *Account) Tombstones() ([]*Tombstone, error) {
+	keys, err := a.ValueKeys(tombstoneKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	tombstones := make([]*Tombstone, 0, len(keys))
+
+	for _, key := range keys {
+		encodedTombstone, err := a.ValueLookup(key)
+		if err != nil {
+			return nil, err
+		}
+
+		var tombstone Tombstone
+
+		err = json.Unmarshal(encodedTombstone, &tombstone)
+		if err != nil {
+			return nil, err
+		}
+
+		tombstones = append(tombstones, &tombstone)
+	}
+
+	sort.Slice(tombstones, func(i, j int) bool {
+		return tombstones[i].Deleted.Before(tombstones[j].Deleted)
+	})
+
+	return tombstones, nil
+}
+
+// credentialDeleted cascades the deletion of a credential to it's exchange records, the
+// presentations that embed it and unreferenced objects, then records a tombstone
+func (a *Account) credentialDeleted(deleted *credential.VerifiableCredential) error {
+	credentialHash, err := deleted.CredentialHash()
+	if err != nil {
+		return err
+	}
+
+	err = a.CredentialExchangeForgetCredential(credentialHash)
+	if err != nil {
+		return err
+	}
+
+	err = a.presentationsEmbeddingDelete(credentialHash)
+	if err != nil {
+		return err
+	}
+
+	// the holder no longer needs to be warned about the credential expiring
+	_ = a.ValueRemove(expiryKeyPrefix + hex.EncodeToString(credentialHash))
+
+	objects, err := a.objectRelease(deleted)
+	if err != nil {
+		return err
+	}
+
+	return a.tombstoneStore(&Tombstone{
+		Kind:           TombstoneCredential,
+		CredentialHash: credentialHash,
+		Type:           deleted.CredentialType(),
+		Objects:        objects,
+	})
+}
+
+// presentationsEmbeddingDelete deletes every stored presentation that embeds a credential
+func (a *Account) presentationsEmbeddingDelete(credentialHash []byte) error {
+	presentationTypes, err := a.presentationTypes()
+	if err != nil {
+		return err
+	}
+
+	for _, presentationType := range presentationTypes {
+		// presentations with more than one type are not returned again once deleted
+		presentations, err := a.PresentationLookupByPresentationType(presentationType)
+		if err != nil {
+			return err
+		}
+
+		for _, presentation := range presentations {
+			embeds := slices.ContainsFunc(presentation.Credentials(), func(c *credential.VerifiableCredential) bool {
+				embeddedHash, err := c.CredentialHash()
+				return err == nil && bytes.Equal(embeddedHash, credentialHash)
+			})
+
+			if !embeds {
+				continue
+			}
+
+			err = a.presentationRemove(presentation.Holder().Address(), presentation)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// presentationDeleted cascades the deletion of a presentation to
+// unreferenced objects, then records a tombstone
+func (a *Account) presentationDeleted(holder *signing.PublicKey, deleted *credential.VerifiablePresentation) error {
+	objects, err := a.objectRelease(deleted.Credentials()...)
+	if err != nil {
+		return err
+	}
+
+	return a.tombstoneStore(&Tombstone{
+		Kind:    TombstonePresentation,
+		Type:    deleted.PresentationType(),
+		Address: holder.String(),
+		Objects: objects,
+	})
+}
+
+// exchangeForgotten removes the licenses an exchange was made under, then records a tombstone
+func (a *Account) exchangeForgotten(withAddress *signing.PublicKey, credentialHash []byte) error {
+	a.exchanges.Lock()
+	err := a.exchangeLicenseRemove(withAddress, credentialHash)
+	a.exchanges.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return a.tombstoneStore(&Tombstone{
+		Kind:           TombstoneExchange,
+		CredentialHash: credentialHash,
+		Address:        withAddress.String(),
+	})
+}
+
+// objectRelease deletes locally stored objects referenced by deleted credentials that are
+// no longer referenced by any stored credential or presentation. Pinned objects are kept
+func (a *Account) objectRelease(deleted ...*credential.VerifiableCredential) ([][]byte, error) {
+	released := make(map[string]bool)
+
+	for _, c := range deleted {
+		a.collectObjectReferences(c, released)
+	}
+
+	if len(released) == 0 {
+		return nil, nil
+	}
+
+	references, err := a.objectReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	for reference := range released {
+		if references[reference] {
+			delete(released, reference)
+		}
+	}
+
+	// objects stored before the object index existed are only indexed once they are found in local storage
+	a.objectIndexBackfill(released)
+
+	var objects [][]byte
+
+	for reference := range released {
+
+		hash, err := hex.DecodeString(reference)
+		if err != nil {
+			continue
+		}
+
+		info, err := a.objectIndexLookup(hash)
+		if err != nil || info.Pinned {
+			// the object is not stored locally, or has been pinned
+			continue
+		}
+
+		err = a.ObjectDelete(hash)
+		if err != nil {
+			return objects, err
+		}
+
+		objects = append(objects, hash)
+	}
+
+	return objects, nil
+}
+
+func (a *Account) tombstoneStore(tombstone *Tombstone) error {
+	tombstone.Deleted = time.Now()
+
+	encodedTombstone, err := json.Marshal(tombstone)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf(
+		"%s%020d/%s/%s%s",
+		tombstoneKeyPrefix,
+		tombstone.Deleted.UnixNano(),
+		tombstone.Kind,
+		hex.EncodeToString(tombstone.CredentialHash),
+		tombstone.Address,
+	)
+
+	return a.ValueStore(key, encodedTombstone)
+}
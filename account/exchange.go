@@ -0,0 +1,133 @@
+package account
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/keypair/signing"
+)
+
+// the native exchange record does not hold a license, so every tracked exchange has exactly
+// one license record, keyed by the time the native record was shared at. Shared at times only
+// have a precision of seconds, so exchanges of a credential with an address in the same second
+// are also keyed by their position in the exchange log
+const exchangeLicenseKeyPrefix = "exchanges/licenses/"
+
+// exchangeLicense the license a credential was shared under. A nil license records
+// that the credential was shared without a license
+type exchangeLicense struct {
+	License *credential.License `json:"license"`
+}
+
+// CredentialExchangeRetentionExpired returns all exchanges where the retention period of the license
+// the credential was shared under has passed
+func (a *Account) CredentialExchangeRetentionExpired(at time.Time) ([]*credential.Exchange, error) {
+	exchanges, err := a.CredentialExchangeLog()
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []*credential.Exchange
+
+	for _, exchange := range exchanges {
+		if exchange.RetentionExpired(at) {
+			expired = append(expired, exchange)
+		}
+	}
+
+	return expired, nil
+}
+
+// exchangeLicenses attaches the license each credential was shared under to the exchanges
+func (a *Account) exchangeLicenses(exchanges []*credential.Exchange) error {
+	seen := make(map[string]int)
+
+	for _, exchange := range exchanges {
+		key := exchangeLicenseKey(exchange.WithAddress(), exchange.CredentialHash(), exchange.SharedAt())
+
+		ordinal := seen[key]
+		seen[key]++
+
+		encodedLicense, err := a.ValueLookup(key + "/" + strconv.Itoa(ordinal))
+		if err != nil {
+			// the exchange was tracked before licenses were recorded
+			continue
+		}
+
+		var license exchangeLicense
+
+		err = json.Unmarshal(encodedLicense, &license)
+		if err != nil {
+			return err
+		}
+
+		exchangeWithLicense(exchange, license.License)
+	}
+
+	return nil
+}
+
+// exchangeLicenseStore records the license of the most recent exchange of a credential with an address.
+// Must be called with the exchanges lock held, after the exchange has been tracked
+func (a *Account) exchangeLicenseStore(withAddress *signing.PublicKey, credentialHash []byte, license *credential.License) error {
+	exchanges, err := a.CredentialExchangeLogWithAddress(withAddress)
+	if err != nil {
+		return err
+	}
+
+	var sharedAt time.Time
+	var ordinal int
+
+	for _, exchange := range exchanges {
+		if !bytes.Equal(exchange.CredentialHash(), credentialHash) {
+			continue
+		}
+
+		switch {
+		case exchange.SharedAt().After(sharedAt):
+			sharedAt = exchange.SharedAt()
+			ordinal = 0
+		case exchange.SharedAt().Equal(sharedAt):
+			// exchanged more than once in the same second
+			ordinal++
+		}
+	}
+
+	encodedLicense, err := json.Marshal(&exchangeLicense{
+		License: license,
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.ValueStore(exchangeLicenseKey(withAddress, credentialHash, sharedAt)+"/"+strconv.Itoa(ordinal), encodedLicense)
+}
+
+// exchangeLicenseRemove removes the license records of every exchange of a credential with an address
+func (a *Account) exchangeLicenseRemove(withAddress *signing.PublicKey, credentialHash []byte) error {
+	keys, err := a.ValueKeys(exchangeLicensePrefix(withAddress, credentialHash))
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		err = a.ValueRemove(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func exchangeLicensePrefix(withAddress *signing.PublicKey, credentialHash []byte) string {
+	return exchangeLicenseKeyPrefix + withAddress.String() + "/" + hex.EncodeToString(credentialHash) + "/"
+}
+
+func exchangeLicenseKey(withAddress *signing.PublicKey, credentialHash []byte, sharedAt time.Time) string {
+	return exchangeLicensePrefix(withAddress, credentialHash) + strconv.FormatInt(sharedAt.Unix(), 10)
+}
@@ -0,0 +1,115 @@
+package account
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+)
+
+const expiryKeyPrefix = "credentials/expiry/"
+
+// CredentialExpiryPolicy defines when the account warns about stored credentials that are about to expire
+type CredentialExpiryPolicy struct {
+	// Warning how long before a credential expires to invoke the OnCredentialExpiring callback. zero disables expiry warnings
+	Warning time.Duration
+	// Interval how often to check stored credentials for expiry. defaults to one hour
+	Interval time.Duration
+}
+
+// CredentialExpiring returns all stored credentials that have not expired, but will expire within the given duration
+func (a *Account) CredentialExpiring(within time.Duration) ([]*credential.VerifiableCredential, error) {
+	credentials, err := a.CredentialLookup()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var expiring []*credential.VerifiableCredential
+
+	for _, c := range credentials {
+		if c.ValidUntil().IsZero() || c.IsExpiredAt(now) {
+			continue
+		}
+
+		if c.IsExpiredAt(now.Add(within)) {
+			expiring = append(expiring, c)
+		}
+	}
+
+	return expiring, nil
+}
+
+// credentialExpiryWatch periodically checks for stored credentials that are
+// about to expire until the account is closed
+func (a *Account) credentialExpiryWatch() {
+	policy := a.config.CredentialExpiry
+
+	if policy.Warning < 1 || a.callbacks.OnCredentialExpiring == nil {
+		return
+	}
+
+	interval := policy.Interval
+	if interval < 1 {
+		interval = time.Hour
+	}
+
+	a.background(func(done chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			err := a.credentialExpiryCheck(policy.Warning)
+			if err != nil {
+				logger()(LogWarn, fmt.Sprintf("failed to check credentials for expiry. error: %s", err))
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// credentialExpiryCheck invokes the OnCredentialExpiring callback once for each credential
+// that will expire within the warning period
+func (a *Account) credentialExpiryCheck(warning time.Duration) error {
+	expiring, err := a.CredentialExpiring(warning)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range expiring {
+		credentialHash, err := c.CredentialHash()
+		if err != nil {
+			return err
+		}
+
+		key := expiryKeyPrefix + hex.EncodeToString(credentialHash)
+
+		_, err = a.ValueLookup(key)
+		if err == nil {
+			// the holder has already been warned about this credential
+			continue
+		}
+
+		err = a.ValueStoreWithExpiry(key, []byte{1}, c.ValidUntil())
+		if err != nil {
+			return err
+		}
+
+		a.callbacks.OnCredentialExpiring(a, c)
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package account
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/joinself/self-go-sdk/object"
+)
+
+const transferKeyPrefix = "objects/transfers/"
+
+// ObjectUploadFrom encrypts and uploads an object from a stream one chunk at a time, returning
+// a manifest object describing the uploaded chunks. The manifest object can be shared in place of
+// the original object and downloaded with ObjectDownloadTo.
+// If the stream is resumable, chunks that were uploaded by a previous interrupted
+// upload of the same transfer will not be uploaded again
+func (a *Account) ObjectUploadFrom(stream *object.Stream) (*object.Object, error) {
+	manifest := &object.Manifest{
+		MimeType: stream.MimeType(),
+	}
+
+	var uploaded []*object.Chunk
+
+	if stream.TransferID() != "" {
+		previous, err := a.transferLookup(stream.TransferID())
+		if err == nil {
+			uploaded = previous.Chunks
+		}
+	}
+
+	for i := 0; ; i++ {
+		chunk, size, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if manifest.ChunkSize == 0 {
+			manifest.ChunkSize = size
+		}
+
+		hash := chunk.Hash()
+
+		if i < len(uploaded) && bytes.Equal(uploaded[i].Hash, hash) {
+			// this chunk was uploaded by a previous attempt
+			manifest.Chunks = append(manifest.Chunks, uploaded[i])
+			manifest.Size += int64(size)
+			stream.ReportProgress(manifest.Size)
+			continue
+		}
+
+		err = a.ObjectUpload(chunk, false)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Chunks = append(manifest.Chunks, &object.Chunk{
+			ID:   chunk.Id(),
+			Key:  chunk.Key(),
+			Hash: hash,
+			Size: size,
+		})
+
+		manifest.Size += int64(size)
+
+		if stream.TransferID() != "" {
+			err = a.transferStore(stream.TransferID(), manifest)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		stream.ReportProgress(manifest.Size)
+	}
+
+	if manifest.ChunkSize == 0 {
+		return nil, errors.New("object stream is empty")
+	}
+
+	manifestObject, err := manifest.Object()
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.ObjectUpload(manifestObject, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if stream.TransferID() != "" {
+		err = a.ValueRemove(transferKeyPrefix + stream.TransferID())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return manifestObject, nil
+}
+
+// ObjectDownloadTo downloads and decrypts an object, writing it's contents to a writer.
+// Objects uploaded with ObjectUploadFrom are downloaded one chunk at a time.
+// If the writer implements io.Seeker, the download will resume from the writer's
+// current offset, so a partially downloaded file can be resumed by seeking to it's end.
+// An optional progress callback can be provided to track the download
+func (a *Account) ObjectDownloadTo(obj *object.Object, writer io.Writer, progress ...object.ProgressFunc) error {
+	var offset int64
+
+	if seeker, ok := writer.(io.Seeker); ok {
+		current, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+
+		offset = current
+	}
+
+	err := a.ObjectDownload(obj)
+	if err != nil {
+		return err
+	}
+
+	if !object.IsManifest(obj) {
+		data := obj.Data()
+
+		if offset > int64(len(data)) {
+			return errors.New("download offset exceeds object size")
+		}
+
+		_, err = writer.Write(data[offset:])
+		if err != nil {
+			return err
+		}
+
+		reportProgress(progress, int64(len(data)), int64(len(data)))
+
+		return nil
+	}
+
+	manifest, err := object.DecodeManifest(obj.Data())
+	if err != nil {
+		return err
+	}
+
+	if offset > manifest.Size {
+		return errors.New("download offset exceeds object size")
+	}
+
+	var size int64
+
+	for _, c := range manifest.Chunks {
+		if c.Size < 0 {
+			return errors.New("object chunk size is invalid")
+		}
+
+		size += int64(c.Size)
+	}
+
+	if size != manifest.Size {
+		return errors.New("object chunk sizes do not match manifest size")
+	}
+
+	var position int64
+
+	for _, c := range manifest.Chunks {
+		if position+int64(c.Size) <= offset {
+			// this chunk was written by a previous download
+			position += int64(c.Size)
+			continue
+		}
+
+		chunk, err := object.FromRemote(c.ID, c.Key, object.MimeTypeChunk)
+		if err != nil {
+			return err
+		}
+
+		err = a.ObjectDownload(chunk)
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(chunk.Hash(), c.Hash) {
+			return errors.New("object chunk hash does not match manifest")
+		}
+
+		data := chunk.Data()
+
+		if int64(len(data)) != int64(c.Size) {
+			return errors.New("object chunk size does not match manifest")
+		}
+
+		if position < offset {
+			data = data[offset-position:]
+		}
+
+		_, err = writer.Write(data)
+		if err != nil {
+			return err
+		}
+
+		position += int64(c.Size)
+
+		reportProgress(progress, position, manifest.Size)
+	}
+
+	return nil
+}
+
+func (a *Account) transferLookup(transferID string) (*object.Manifest, error) {
+	encodedManifest, err := a.ValueLookup(transferKeyPrefix + transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest object.Manifest
+
+	return &manifest, json.Unmarshal(encodedManifest, &manifest)
+}
+
+func (a *Account) transferStore(transferID string, manifest *object.Manifest) error {
+	encodedManifest, err := manifest.Encode()
+	if err != nil {
+		return err
+	}
+
+	return a.ValueStore(transferKeyPrefix+transferID, encodedManifest)
+}
+
+func reportProgress(progress []object.ProgressFunc, transferred, total int64) {
+	for i := range progress {
+		progress[i](transferred, total)
+	}
+}
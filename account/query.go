@@ -0,0 +1,98 @@
+package account
+
+import (
+	"sort"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/credential/predicate"
+)
+
+const (
+	CredentialSortNone CredentialSort = iota
+	CredentialSortCreated
+	CredentialSortValidFrom
+)
+
+// CredentialSort the field used to order the results of a credential query
+type CredentialSort int
+
+func (s CredentialSort) String() string {
+	switch s {
+	case CredentialSortCreated:
+		return "Created"
+	case CredentialSortValidFrom:
+		return "ValidFrom"
+	default:
+		return "None"
+	}
+}
+
+// CredentialQueryOptions controls the ordering and pagination of a credential query
+type CredentialQueryOptions struct {
+	// Sort the field to order matching credentials by
+	Sort CredentialSort
+	// Descending orders matching credentials from newest to oldest
+	Descending bool
+	// Offset the number of matching credentials to skip
+	Offset int
+	// Limit the maximum number of credentials to return. zero returns all matching credentials
+	Limit int
+}
+
+// CredentialQuery returns stored credentials that individually satisfy the predicate tree.
+// A nil tree matches all stored credentials
+func (a *Account) CredentialQuery(tree *predicate.Tree, options ...*CredentialQueryOptions) ([]*credential.VerifiableCredential, error) {
+	credentials, err := a.CredentialLookup()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts CredentialQueryOptions
+	if len(options) > 0 && options[0] != nil {
+		opts = *options[0]
+	}
+
+	var matches []*credential.VerifiableCredential
+
+	for _, c := range credentials {
+		if tree != nil {
+			_, ok := tree.FindOptimalMatch([]*credential.VerifiableCredential{c})
+			if !ok {
+				continue
+			}
+		}
+
+		matches = append(matches, c)
+	}
+
+	if opts.Sort != CredentialSortNone {
+		instant := func(c *credential.VerifiableCredential) time.Time {
+			if opts.Sort == CredentialSortValidFrom {
+				return c.ValidFrom()
+			}
+			return c.Created()
+		}
+
+		sort.SliceStable(matches, func(i, j int) bool {
+			if opts.Descending {
+				return instant(matches[i]).After(instant(matches[j]))
+			}
+			return instant(matches[i]).Before(instant(matches[j]))
+		})
+	}
+
+	if opts.Offset > 0 {
+		if opts.Offset >= len(matches) {
+			return nil, nil
+		}
+
+		matches = matches[opts.Offset:]
+	}
+
+	if opts.Limit > 0 && opts.Limit < len(matches) {
+		matches = matches[:opts.Limit]
+	}
+
+	return matches, nil
+}
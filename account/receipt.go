@@ -0,0 +1,135 @@
+package account
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/joinself/self-go-sdk/event"
+	"github.com/joinself/self-go-sdk/keypair/signing"
+	"github.com/joinself/self-go-sdk/message"
+)
+
+const (
+	receiptKeyPrefix          = "receipts/"
+	receiptRecipientKeyPrefix = "receipts/recipients/"
+)
+
+// MessageStatus returns the delivery status of a message we have sent, referenced by the id of the messages content.
+// Returns DeliveryStatusUnknown for messages that have no recorded status
+func (a *Account) MessageStatus(contentID []byte) (message.DeliveryStatus, error) {
+	value, err := a.ValueLookup(receiptKey(contentID))
+	if err != nil {
+		// no status has been recorded for the message
+		return message.DeliveryStatusUnknown, nil
+	}
+
+	if len(value) != 1 {
+		return message.DeliveryStatusUnknown, nil
+	}
+
+	return message.DeliveryStatus(value[0]), nil
+}
+
+// MessageRead sends a receipt to an address, marking the given messages as read
+func (a *Account) MessageRead(toAddress *signing.PublicKey, messageIDs ...[]byte) error {
+	content, err := message.NewReceipt().
+		Read(messageIDs...).
+		Finish()
+
+	if err != nil {
+		return err
+	}
+
+	return a.MessageSend(toAddress, content)
+}
+
+// receiptUpdate advances the delivery status of a message. the status of a message
+// only ever moves forward, so a late acknowledgement from the server will not
+// overwrite a delivered or read receipt from the recipient
+func receiptUpdate(a *Account, contentID []byte, deliveryStatus message.DeliveryStatus) {
+	a.receipts.Lock()
+	defer a.receipts.Unlock()
+
+	current, _ := a.MessageStatus(contentID)
+	if current >= deliveryStatus {
+		return
+	}
+
+	err := a.ValueStore(receiptKey(contentID), []byte{byte(deliveryStatus)})
+	if err != nil {
+		logger()(LogWarn, fmt.Sprintf("failed to update message delivery status. error: %s", err))
+	}
+}
+
+// receiptHandle processes a receipt sent to us by the recipient of one of our messages
+func receiptHandle(a *Account, msg *event.Message) {
+	receipt, err := message.DecodeReceipt(msg.Content())
+	if err != nil {
+		logger()(LogWarn, fmt.Sprintf("failed to decode receipt. error: %s", err))
+		return
+	}
+
+	for _, id := range receipt.Delivered() {
+		if receiptFromRecipient(a, id, msg.FromAddress()) {
+			receiptUpdate(a, id, message.DeliveryStatusDelivered)
+		}
+	}
+
+	for _, id := range receipt.Read() {
+		if receiptFromRecipient(a, id, msg.FromAddress()) {
+			receiptUpdate(a, id, message.DeliveryStatusRead)
+		}
+	}
+
+	if a.callbacks.OnReceipt != nil {
+		a.callbacks.OnReceipt(a, msg, receipt)
+	}
+}
+
+// receiptRecipientStore records the address a message was sent to, so only
+// receipts from the recipient of the message can update it's status
+func receiptRecipientStore(a *Account, contentID []byte, toAddress *signing.PublicKey) error {
+	return a.ValueStore(receiptRecipientKey(contentID), toAddress.Bytes())
+}
+
+// receiptFromRecipient returns true if the message was sent to the address a receipt was sent from
+func receiptFromRecipient(a *Account, contentID []byte, fromAddress *signing.PublicKey) bool {
+	var recipient *signing.PublicKey
+
+	value, err := a.ValueLookup(receiptRecipientKey(contentID))
+	if err == nil {
+		recipient = signing.FromBytes(value)
+	}
+
+	if recipient == nil || !recipient.Matches(fromAddress) {
+		logger()(LogWarn, fmt.Sprintf("ignoring receipt from %s for a message that was not sent to it", fromAddress.String()))
+		return false
+	}
+
+	return true
+}
+
+// receiptDeliver sends a delivery receipt back to the sender of a message
+func receiptDeliver(a *Account, msg *event.Message) {
+	content, err := message.NewReceipt().
+		Delivered(msg.ID()).
+		Finish()
+
+	if err != nil {
+		logger()(LogWarn, fmt.Sprintf("failed to create delivery receipt. error: %s", err))
+		return
+	}
+
+	err = a.MessageSend(msg.FromAddress(), content)
+	if err != nil {
+		logger()(LogWarn, fmt.Sprintf("failed to send delivery receipt. error: %s", err))
+	}
+}
+
+func receiptKey(contentID []byte) string {
+	return receiptKeyPrefix + hex.EncodeToString(contentID)
+}
+
+func receiptRecipientKey(contentID []byte) string {
+	return receiptRecipientKeyPrefix + hex.EncodeToString(contentID)
+}
@@ -0,0 +1,492 @@
+package credential
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	SexMale        Sex = "M"
+	SexFemale      Sex = "F"
+	SexUnspecified Sex = "X"
+)
+
+var (
+	// ErrUnexpectedCredentialType returned when decoding the claims of a credential that is not of the requested type
+	ErrUnexpectedCredentialType = errors.New("credential is not of the requested type")
+	// ErrInvalidClaim returned when a claim cannot be decoded to it's typed value
+	ErrInvalidClaim = errors.New("credential claim invalid")
+)
+
+// Sex the sex of a passport holder, as recorded in the document's machine readable zone
+type Sex string
+
+// EmailClaims the claims of an email credential
+type EmailClaims struct {
+	EmailAddress string
+}
+
+// PhoneClaims the claims of a phone credential
+type PhoneClaims struct {
+	// PhoneNumber the phone number in E.164 format
+	PhoneNumber string
+}
+
+// PassportClaims the claims of a passport credential. Claims that were
+// not included in the credential are left as their zero value
+type PassportClaims struct {
+	DocumentNumber    string
+	GivenNames        string
+	Surname           string
+	Sex               Sex
+	Nationality       string
+	DateOfBirth       time.Time
+	DateOfExpiration  time.Time
+	CountryOfIssuance string
+	MRZ               string
+	ImageType         string
+	ImageHash         []byte
+	TargetImageHash   []byte
+}
+
+// OrganisationClaims the claims of an organisation credential
+type OrganisationClaims struct {
+	OrganisationName string
+}
+
+// ApplicationClaims the claims of an application credential
+type ApplicationClaims struct {
+	ApplicationName string
+	// SubsidiaryOf the address of the organisation the application belongs to, if any
+	SubsidiaryOf string
+}
+
+// FacialComparisonClaims the claims of a facial comparison or liveness and facial comparison credential
+type FacialComparisonClaims struct {
+	// Liveness true if the comparison included a liveness check
+	Liveness        bool
+	SourceImageHash []byte
+	TargetImageHash []byte
+	// Challenge and ComputedHashes are only set by liveness checks
+	Challenge      string
+	ComputedHashes [][]byte
+}
+
+// BiometricAnchorClaims the claims of a biometric anchor credential
+type BiometricAnchorClaims struct {
+	SourceImageHash []byte
+	ComputedHashes  [][]byte
+}
+
+// AsEmail decodes the claims of an email credential
+func AsEmail(vc *VerifiableCredential) (*EmailClaims, error) {
+	r, err := claimsOf(vc, CredentialTypeEmail, "email")
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &EmailClaims{
+		EmailAddress: r.string("emailAddress"),
+	}
+
+	return claims, r.err
+}
+
+// AsPhone decodes the claims of a phone credential
+func AsPhone(vc *VerifiableCredential) (*PhoneClaims, error) {
+	r, err := claimsOf(vc, CredentialTypePhone, "phone")
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &PhoneClaims{
+		PhoneNumber: r.string("phoneNumber"),
+	}
+
+	return claims, r.err
+}
+
+// AsPassport decodes the claims of a passport credential, parsing it's dates and validating the holder's sex
+func AsPassport(vc *VerifiableCredential) (*PassportClaims, error) {
+	r, err := claimsOf(vc, CredentialTypePassport, "passport")
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &PassportClaims{
+		DocumentNumber:    r.string("documentNumber"),
+		GivenNames:        r.string("givenNames"),
+		Surname:           r.string("surname"),
+		Sex:               r.sex("sex"),
+		Nationality:       r.string("nationality"),
+		DateOfBirth:       r.date("dateOfBirth"),
+		DateOfExpiration:  r.date("dateOfExpiration"),
+		CountryOfIssuance: r.string("countryOfIssuance"),
+		MRZ:               r.string("mrz"),
+		ImageType:         r.string("imageType"),
+		ImageHash:         r.hash("imageHash"),
+		TargetImageHash:   r.hash("targetImageHash"),
+	}
+
+	return claims, r.err
+}
+
+// AsOrganisation decodes the claims of an organisation credential
+func AsOrganisation(vc *VerifiableCredential) (*OrganisationClaims, error) {
+	r, err := claimsOf(vc, CredentialTypeOrganisation, "organisation")
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &OrganisationClaims{
+		OrganisationName: r.string("organisationName"),
+	}
+
+	return claims, r.err
+}
+
+// AsApplication decodes the claims of an application credential
+func AsApplication(vc *VerifiableCredential) (*ApplicationClaims, error) {
+	r, err := claimsOf(vc, CredentialTypeApplication, "application")
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &ApplicationClaims{
+		ApplicationName: r.string("applicationName"),
+		SubsidiaryOf:    r.string("subsidiaryOf"),
+	}
+
+	return claims, r.err
+}
+
+// AsFacialComparison decodes the claims of a facial comparison credential,
+// or a liveness and facial comparison credential
+func AsFacialComparison(vc *VerifiableCredential) (*FacialComparisonClaims, error) {
+	if slices.Contains(vc.CredentialType(), CredentialTypeLivenessAndFacialComparison) {
+		r, err := claimsOf(vc, CredentialTypeLivenessAndFacialComparison, "livenessAndFacialComparison")
+		if err != nil {
+			return nil, err
+		}
+
+		claims := &FacialComparisonClaims{
+			Liveness:        true,
+			SourceImageHash: r.hash("sourceImageHash"),
+			TargetImageHash: r.hash("targetImageHash"),
+			Challenge:       r.string("challenge"),
+			ComputedHashes:  r.hashes("computedHashes"),
+		}
+
+		return claims, r.err
+	}
+
+	r, err := claimsOf(vc, CredentialTypeFacialComparison, "facialComparison")
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &FacialComparisonClaims{
+		SourceImageHash: r.hash("sourceImageHash"),
+		TargetImageHash: r.hash("targetImageHash"),
+	}
+
+	return claims, r.err
+}
+
+// AsBiometricAnchor decodes the claims of a biometric anchor credential
+func AsBiometricAnchor(vc *VerifiableCredential) (*BiometricAnchorClaims, error) {
+	r, err := claimsOf(vc, CredentialTypeBiometricAnchor, "biometricAnchor")
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &BiometricAnchorClaims{
+		SourceImageHash: r.hash("sourceImageHash"),
+		ComputedHashes:  r.hashes("computedHashes"),
+	}
+
+	return claims, r.err
+}
+
+// Email sets the type and claims of an email credential
+func (b *CredentialBuilder) Email(claims *EmailClaims) *CredentialBuilder {
+	return b.typedClaims(CredentialTypeEmail, "email", claimObject{
+		"emailAddress": claims.EmailAddress,
+	})
+}
+
+// Phone sets the type and claims of a phone credential
+func (b *CredentialBuilder) Phone(claims *PhoneClaims) *CredentialBuilder {
+	return b.typedClaims(CredentialTypePhone, "phone", claimObject{
+		"phoneNumber": claims.PhoneNumber,
+	})
+}
+
+// Passport sets the type and claims of a passport credential. Dates are encoded as
+// full dates, and claims that are not set are omitted from the credential
+func (b *CredentialBuilder) Passport(claims *PassportClaims) *CredentialBuilder {
+	if claims.Sex != "" && !claims.Sex.valid() {
+		b.err = fmt.Errorf("%w: /passport/sex must be one of M, F or X", ErrInvalidClaim)
+	}
+
+	object := claimObject{}
+
+	object.setString("documentNumber", claims.DocumentNumber)
+	object.setString("givenNames", claims.GivenNames)
+	object.setString("surname", claims.Surname)
+	object.setString("sex", string(claims.Sex))
+	object.setString("nationality", claims.Nationality)
+	object.setDate("dateOfBirth", claims.DateOfBirth)
+	object.setDate("dateOfExpiration", claims.DateOfExpiration)
+	object.setString("countryOfIssuance", claims.CountryOfIssuance)
+	object.setString("mrz", claims.MRZ)
+	object.setString("imageType", claims.ImageType)
+	object.setHash("imageHash", claims.ImageHash)
+	object.setHash("targetImageHash", claims.TargetImageHash)
+
+	return b.typedClaims(CredentialTypePassport, "passport", object)
+}
+
+// Organisation sets the type and claims of an organisation credential
+func (b *CredentialBuilder) Organisation(claims *OrganisationClaims) *CredentialBuilder {
+	return b.typedClaims(CredentialTypeOrganisation, "organisation", claimObject{
+		"organisationName": claims.OrganisationName,
+	})
+}
+
+// Application sets the type and claims of an application credential
+func (b *CredentialBuilder) Application(claims *ApplicationClaims) *CredentialBuilder {
+	object := claimObject{
+		"applicationName": claims.ApplicationName,
+	}
+
+	object.setString("subsidiaryOf", claims.SubsidiaryOf)
+
+	return b.typedClaims(CredentialTypeApplication, "application", object)
+}
+
+// FacialComparison sets the type and claims of a facial comparison credential,
+// or a liveness and facial comparison credential if the claims include a liveness check
+func (b *CredentialBuilder) FacialComparison(claims *FacialComparisonClaims) *CredentialBuilder {
+	object := claimObject{}
+
+	object.setHash("sourceImageHash", claims.SourceImageHash)
+	object.setHash("targetImageHash", claims.TargetImageHash)
+
+	if !claims.Liveness {
+		return b.typedClaims(CredentialTypeFacialComparison, "facialComparison", object)
+	}
+
+	object.setString("challenge", claims.Challenge)
+	object.setHashes("computedHashes", claims.ComputedHashes)
+
+	return b.typedClaims(CredentialTypeLivenessAndFacialComparison, "livenessAndFacialComparison", object)
+}
+
+// BiometricAnchor sets the type and claims of a biometric anchor credential
+func (b *CredentialBuilder) BiometricAnchor(claims *BiometricAnchorClaims) *CredentialBuilder {
+	object := claimObject{}
+
+	object.setHash("sourceImageHash", claims.SourceImageHash)
+	object.setHashes("computedHashes", claims.ComputedHashes)
+
+	return b.typedClaims(CredentialTypeBiometricAnchor, "biometricAnchor", object)
+}
+
+func (b *CredentialBuilder) typedClaims(credentialType, key string, object claimObject) *CredentialBuilder {
+	return b.CredentialType(credentialType).CredentialSubjectClaims(map[string]interface{}{
+		key: map[string]interface{}(object),
+	})
+}
+
+func (s Sex) valid() bool {
+	switch s {
+	case SexMale, SexFemale, SexUnspecified:
+		return true
+	default:
+		return false
+	}
+}
+
+// claimObject the claims of a built-in credential type, which are nested under a single key of the subject
+type claimObject map[string]interface{}
+
+func (o claimObject) setString(key, value string) {
+	if value != "" {
+		o[key] = value
+	}
+}
+
+func (o claimObject) setDate(key string, value time.Time) {
+	if !value.IsZero() {
+		o[key] = value.Format(time.DateOnly)
+	}
+}
+
+func (o claimObject) setHash(key string, value []byte) {
+	if len(value) > 0 {
+		o[key] = hex.EncodeToString(value)
+	}
+}
+
+func (o claimObject) setHashes(key string, values [][]byte) {
+	if len(values) == 0 {
+		return
+	}
+
+	hashes := make([]interface{}, len(values))
+
+	for i, value := range values {
+		hashes[i] = hex.EncodeToString(value)
+	}
+
+	o[key] = hashes
+}
+
+// claimReader reads the claims of a built-in credential type, recording the first claim that could not be decoded
+type claimReader struct {
+	object  map[string]interface{}
+	pointer string
+	err     error
+}
+
+// claimsOf checks the credential is of the given type and validates it's claims against
+// the type's schema, returning a reader for the claims nested under the given key
+func claimsOf(vc *VerifiableCredential, credentialType, key string) (*claimReader, error) {
+	if !slices.Contains(vc.CredentialType(), credentialType) {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedCredentialType, credentialType)
+	}
+
+	claims, err := vc.CredentialSubjectClaims()
+	if err != nil {
+		return nil, err
+	}
+
+	// the subject's id is not a claim
+	delete(claims, "id")
+
+	err = ValidateClaims([]string{credentialType}, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &claimReader{
+		pointer: "/" + key,
+	}
+
+	switch object := claims[key].(type) {
+	case map[string]interface{}:
+		r.object = object
+	case nil:
+		r.object = map[string]interface{}{}
+	default:
+		return nil, fmt.Errorf("%w: %s must be an object", ErrInvalidClaim, r.pointer)
+	}
+
+	return r, nil
+}
+
+func (r *claimReader) fail(key, format string, args ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: %s/%s %s", ErrInvalidClaim, r.pointer, key, fmt.Sprintf(format, args...))
+	}
+}
+
+func (r *claimReader) string(key string) string {
+	value, ok := r.object[key]
+	if !ok {
+		return ""
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		r.fail(key, "must be a string")
+	}
+
+	return s
+}
+
+func (r *claimReader) sex(key string) Sex {
+	value := r.string(key)
+	if value == "" {
+		return ""
+	}
+
+	s := Sex(strings.ToUpper(value))
+	if !s.valid() {
+		r.fail(key, "must be one of M, F or X")
+		return ""
+	}
+
+	return s
+}
+
+// date parses a date, which may either be a full date or a date time
+func (r *claimReader) date(key string) time.Time {
+	value := r.string(key)
+	if value == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t
+		}
+	}
+
+	r.fail(key, "must be a date or date time")
+
+	return time.Time{}
+}
+
+func (r *claimReader) hash(key string) []byte {
+	value := r.string(key)
+	if value == "" {
+		return nil
+	}
+
+	h, err := hex.DecodeString(value)
+	if err != nil {
+		r.fail(key, "must be hex encoded")
+	}
+
+	return h
+}
+
+func (r *claimReader) hashes(key string) [][]byte {
+	value, ok := r.object[key]
+	if !ok {
+		return nil
+	}
+
+	values, ok := value.([]interface{})
+	if !ok {
+		r.fail(key, "must be an array")
+		return nil
+	}
+
+	hashes := make([][]byte, 0, len(values))
+
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			r.fail(fmt.Sprintf("%s/%d", key, i), "must be a string")
+			return nil
+		}
+
+		h, err := hex.DecodeString(s)
+		if err != nil {
+			r.fail(fmt.Sprintf("%s/%d", key, i), "must be hex encoded")
+			return nil
+		}
+
+		hashes = append(hashes, h)
+	}
+
+	return hashes
+}
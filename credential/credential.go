@@ -94,12 +94,16 @@ func credentialPtr(c *Credential) *C.self_credential {
 }
 
 type CredentialBuilder struct {
-	ptr *C.self_credential_builder
+	ptr            *C.self_credential_builder
+	credentialType []string
+	claims         map[string]interface{}
+	err            error
 }
 
 func newCredentialBuilder(ptr *C.self_credential_builder) *CredentialBuilder {
 	b := &CredentialBuilder{
-		ptr: ptr,
+		ptr:    ptr,
+		claims: make(map[string]interface{}),
 	}
 
 	runtime.AddCleanup(b, func(ptr *C.self_credential_builder) {
@@ -140,6 +144,8 @@ func NewCredential() *CredentialBuilder {
 
 // CredentialType sets the type of credential
 func (b *CredentialBuilder) CredentialType(credentialType ...string) *CredentialBuilder {
+	b.credentialType = credentialType
+
 	collection := toCredentialTypeCollection(credentialType)
 
 	C.self_credential_builder_credential_type(
@@ -179,6 +185,8 @@ func (b *CredentialBuilder) CredentialSubjectClaim(claimKey, claimValue string)
 		value,
 	)
 
+	b.claims[claimKey] = claimValue
+
 	return b
 }
 
@@ -202,6 +210,10 @@ func (b *CredentialBuilder) CredentialSubjectClaims(claims map[string]interface{
 		(C.size_t)(claimLength),
 	)
 
+	for key, value := range claims {
+		b.claims[key] = value
+	}
+
 	return b
 }
 
@@ -242,10 +254,21 @@ func (b *CredentialBuilder) SignWith(signer *signing.PublicKey, issuedAt time.Ti
 	return b
 }
 
-// Finish generates and prepares the credential for being signed by an account
+// Finish generates and prepares the credential for being signed by an account.
+// Returns an error if the claims do not conform to the schema registered for the credential's type,
+// or if the claims set by a typed builder such as Passport are invalid
 func (b *CredentialBuilder) Finish() (*Credential, error) {
 	var credential *C.self_credential
 
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	err := ValidateClaims(b.credentialType, b.claims)
+	if err != nil {
+		return nil, err
+	}
+
 	result := C.self_credential_builder_finish(
 		b.ptr,
 		&credential,
@@ -419,6 +442,25 @@ func (c *VerifiableCredential) ValidFrom() time.Time {
 	return time.Unix(int64(validFrom), 0)
 }
 
+// ValidUntil returns the end of the credential's validity period, or the zero time if the credential does not expire
+func (c *VerifiableCredential) ValidUntil() time.Time {
+	validUntil := C.self_verifiable_credential_valid_until(
+		c.ptr,
+	)
+
+	if validUntil == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(validUntil), 0)
+}
+
+// IsExpiredAt returns true if the credential's validity period has ended at the given time
+func (c *VerifiableCredential) IsExpiredAt(at time.Time) bool {
+	validUntil := c.ValidUntil()
+	return !validUntil.IsZero() && at.After(validUntil)
+}
+
 // Created returns the time that the credential was created
 func (c *VerifiableCredential) Created() time.Time {
 	created := C.self_verifiable_credential_created(
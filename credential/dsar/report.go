@@ -0,0 +1,357 @@
+// Package dsar generates data subject access reports that describe which
+// credentials have been shared, who with, when and under what terms
+package dsar
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joinself/self-go-sdk/account"
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/keypair/signing"
+)
+
+var (
+	// ErrNotSigned returned when verifying a report that has not been signed
+	ErrNotSigned = errors.New("report is not signed")
+	// ErrInvalidSignature returned when a reports signature does not match its contents
+	ErrInvalidSignature = errors.New("report signature is invalid")
+)
+
+// Signer signs payloads with a key held by an account
+type Signer interface {
+	KeychainSign(address *signing.PublicKey, payload []byte) ([]byte, error)
+}
+
+// Entry describes a single exchange of a credential
+type Entry struct {
+	// SharedAt the time the credential was shared
+	SharedAt time.Time `json:"sharedAt"`
+	// Recipient the address the credential was shared with
+	Recipient string `json:"recipient"`
+	// RecipientIdentity the document address of the recipients pairwise identity, if known
+	RecipientIdentity string `json:"recipientIdentity,omitempty"`
+	// CredentialHash the hash of the shared credential
+	CredentialHash string `json:"credentialHash"`
+	// CredentialType the type of the shared credential. empty if the credential is no longer stored
+	CredentialType []string `json:"credentialType,omitempty"`
+	// Issuer the issuer of the shared credential
+	Issuer string `json:"issuer,omitempty"`
+	// Claims json pointers to the claims contained in the shared credential
+	Claims []string `json:"claims,omitempty"`
+	// License the license the credential was shared under
+	License *credential.License `json:"license,omitempty"`
+	// RetentionExpires the time the recipient must no longer retain the credential. zero if unlimited
+	RetentionExpires time.Time `json:"retentionExpires,omitzero"`
+}
+
+// Report a data subject access report
+type Report struct {
+	Generated time.Time `json:"generated"`
+	Entries   []*Entry  `json:"entries"`
+	Signer    string    `json:"signer,omitempty"`
+	Signature string    `json:"signature,omitempty"`
+}
+
+// Generate generates a report of all credentials the account has shared. If addresses
+// are provided, only credentials shared with those addresses are included
+func Generate(acc *account.Account, withAddress ...*signing.PublicKey) (*Report, error) {
+	var exchanges []*credential.Exchange
+
+	if len(withAddress) == 0 {
+		log, err := acc.CredentialExchangeLog()
+		if err != nil {
+			return nil, err
+		}
+
+		exchanges = log
+	}
+
+	for _, address := range withAddress {
+		log, err := acc.CredentialExchangeLogWithAddress(address)
+		if err != nil {
+			return nil, err
+		}
+
+		exchanges = append(exchanges, log...)
+	}
+
+	report := &Report{
+		Generated: time.Now().UTC(),
+		Entries:   make([]*Entry, 0, len(exchanges)),
+	}
+
+	for _, exchange := range exchanges {
+		recipient := exchange.WithAddress()
+		credentialHash := exchange.CredentialHash()
+
+		entry := &Entry{
+			SharedAt:       exchange.SharedAt().UTC(),
+			Recipient:      recipient.String(),
+			CredentialHash: hex.EncodeToString(credentialHash),
+			License:        exchange.UnderLicense(),
+		}
+
+		entry.RetentionExpires = entry.License.RetentionExpiresAt(entry.SharedAt)
+
+		identity, err := acc.ConnectionPairwiseBySender(recipient)
+		if err == nil && identity != nil {
+			entry.RecipientIdentity = identity.DocumentAddress().String()
+		}
+
+		credentials, err := acc.CredentialLookupByCredentialHash(credentialHash)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(credentials) > 0 {
+			entry.CredentialType = credentials[0].CredentialType()
+			entry.Issuer = credentials[0].Issuer().String()
+
+			claims, err := credentials[0].CredentialSubjectClaims()
+			if err != nil {
+				return nil, err
+			}
+
+			// the subject's id is not a claim
+			delete(claims, "id")
+
+			entry.Claims = claimPointers("", claims)
+		}
+
+		report.Entries = append(report.Entries, entry)
+	}
+
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		return report.Entries[i].SharedAt.Before(report.Entries[j].SharedAt)
+	})
+
+	return report, nil
+}
+
+// Decode decodes a json encoded report
+func Decode(encodedReport []byte) (*Report, error) {
+	var report Report
+	return &report, json.Unmarshal(encodedReport, &report)
+}
+
+// Sign signs the report with a signing key held by the signer
+func (r *Report) Sign(signer Signer, signingKey *signing.PublicKey) error {
+	r.Signer = signingKey.String()
+	r.Signature = ""
+
+	payload, err := r.payload()
+	if err != nil {
+		return err
+	}
+
+	signature, err := signer.KeychainSign(signingKey, payload)
+	if err != nil {
+		return err
+	}
+
+	r.Signature = hex.EncodeToString(signature)
+
+	return nil
+}
+
+// Verify verifies the reports signature, returning the key that signed it
+func (r *Report) Verify() (*signing.PublicKey, error) {
+	if r.Signer == "" || r.Signature == "" {
+		return nil, ErrNotSigned
+	}
+
+	signature, err := hex.DecodeString(r.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return nil, ErrInvalidSignature
+	}
+
+	signingKey := signing.FromAddress(r.Signer)
+	if signingKey == nil {
+		return nil, ErrInvalidSignature
+	}
+
+	payload, err := r.payload()
+	if err != nil {
+		return nil, err
+	}
+
+	// signing keys are encoded with a leading key type
+	publicKey := ed25519.PublicKey(signingKey.Bytes()[1:])
+
+	if !ed25519.Verify(publicKey, payload, signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	return signingKey, nil
+}
+
+// JSON encodes the report as json
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// CSV encodes the entries of the report as csv, with a header row
+func (r *Report) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+
+	err := w.Write([]string{
+		"shared_at",
+		"recipient",
+		"recipient_identity",
+		"credential_hash",
+		"credential_type",
+		"issuer",
+		"claims",
+		"purpose",
+		"retention_expires",
+		"onward_sharing",
+		"jurisdiction",
+		"agreement",
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range r.Entries {
+		var purpose, jurisdiction, agreement, retentionExpires string
+		var onwardSharing bool
+
+		if entry.License != nil {
+			purpose = entry.License.Purpose
+			jurisdiction = entry.License.Jurisdiction
+			agreement = hex.EncodeToString(entry.License.Agreement)
+			onwardSharing = entry.License.OnwardSharing
+		}
+
+		if !entry.RetentionExpires.IsZero() {
+			retentionExpires = entry.RetentionExpires.Format(time.RFC3339)
+		}
+
+		err = w.Write([]string{
+			entry.SharedAt.Format(time.RFC3339),
+			entry.Recipient,
+			entry.RecipientIdentity,
+			entry.CredentialHash,
+			strings.Join(entry.CredentialType, ";"),
+			entry.Issuer,
+			strings.Join(entry.Claims, ";"),
+			purpose,
+			retentionExpires,
+			strconv.FormatBool(onwardSharing),
+			jurisdiction,
+			agreement,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+// String renders the report in a human readable form
+func (r *Report) String() string {
+	var b strings.Builder
+
+	b.WriteString("Data subject access report\n")
+	fmt.Fprintf(&b, "Generated: %s\n", r.Generated.Format(time.RFC1123))
+
+	if r.Signer != "" {
+		fmt.Fprintf(&b, "Signed by: %s\n", r.Signer)
+	}
+
+	if len(r.Entries) == 0 {
+		b.WriteString("\nNo credentials have been shared\n")
+	}
+
+	for _, entry := range r.Entries {
+		recipient := entry.Recipient
+		if entry.RecipientIdentity != "" {
+			recipient = fmt.Sprintf("%s (%s)", entry.RecipientIdentity, entry.Recipient)
+		}
+
+		fmt.Fprintf(&b, "\nShared with %s on %s\n", recipient, entry.SharedAt.Format(time.RFC1123))
+
+		if len(entry.CredentialType) > 0 {
+			fmt.Fprintf(&b, "  Credential: %s\n", credential.PrimaryType(entry.CredentialType))
+			fmt.Fprintf(&b, "  Issuer: %s\n", entry.Issuer)
+			fmt.Fprintf(&b, "  Data: %s\n", strings.Join(entry.Claims, ", "))
+		} else {
+			fmt.Fprintf(&b, "  Credential: %s (no longer stored)\n", entry.CredentialHash)
+		}
+
+		if entry.License == nil {
+			b.WriteString("  Terms: none recorded\n")
+			continue
+		}
+
+		fmt.Fprintf(&b, "  Purpose: %s\n", entry.License.Purpose)
+
+		if entry.RetentionExpires.IsZero() {
+			b.WriteString("  Retention: unlimited\n")
+		} else {
+			fmt.Fprintf(&b, "  Retention: until %s\n", entry.RetentionExpires.Format(time.RFC1123))
+		}
+
+		if entry.License.OnwardSharing {
+			b.WriteString("  Onward sharing: permitted\n")
+		} else {
+			b.WriteString("  Onward sharing: not permitted\n")
+		}
+
+		if entry.License.Jurisdiction != "" {
+			fmt.Fprintf(&b, "  Jurisdiction: %s\n", entry.License.Jurisdiction)
+		}
+
+		if len(entry.License.Agreement) > 0 {
+			fmt.Fprintf(&b, "  Agreement: %s\n", hex.EncodeToString(entry.License.Agreement))
+		}
+	}
+
+	return b.String()
+}
+
+// payload returns the signed contents of the report
+func (r *Report) payload() ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = ""
+
+	return json.Marshal(&unsigned)
+}
+
+// claimPointers returns json pointers to each of the claims values
+func claimPointers(prefix string, claims map[string]interface{}) []string {
+	var pointers []string
+
+	for key, value := range claims {
+		pointer := prefix + "/" + strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+
+		nested, ok := value.(map[string]interface{})
+		if ok && len(nested) > 0 {
+			pointers = append(pointers, claimPointers(pointer, nested)...)
+			continue
+		}
+
+		pointers = append(pointers, pointer)
+	}
+
+	sort.Strings(pointers)
+
+	return pointers
+}
@@ -17,7 +17,8 @@ import (
 )
 
 type Exchange struct {
-	ptr *C.self_credential_exchange
+	ptr     *C.self_credential_exchange
+	license *License
 }
 
 func newExchange(ptr *C.self_credential_exchange) *Exchange {
@@ -57,8 +58,9 @@ func (e *Exchange) CredentialHash() []byte {
 	return credentialHash
 }
 
+// UnderLicense returns the license the credential was shared under. returns nil if no license was specified
 func (e *Exchange) UnderLicense() *License {
-	return nil
+	return e.license
 }
 
 func (e *Exchange) SharedAt() time.Time {
@@ -67,6 +69,16 @@ func (e *Exchange) SharedAt() time.Time {
 	)), 0)
 }
 
+// RetentionExpired returns true if the licenses retention period for the exchanged credential has passed
+func (e *Exchange) RetentionExpired(at time.Time) bool {
+	expiresAt := e.license.RetentionExpiresAt(e.SharedAt())
+	return !expiresAt.IsZero() && at.After(expiresAt)
+}
+
+func exchangeWithLicense(e *Exchange, license *License) {
+	e.license = license
+}
+
 func fromCredentialExchangeCollection(collection *C.self_collection_credential_exchange) []*Exchange {
 	collectionLen := int(C.self_collection_credential_exchange_len(
 		collection,
@@ -0,0 +1,406 @@
+package credential
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// ReasonInvalidSignature the credential's signature could not be verified
+	ReasonInvalidSignature Reason = "invalid_signature"
+	// ReasonInvalidPresentation the presentation the credential was presented in could not be verified
+	ReasonInvalidPresentation Reason = "invalid_presentation"
+	// ReasonUntrustedIssuer the credential's issuer is not a trusted issuer of the credential's type
+	ReasonUntrustedIssuer Reason = "untrusted_issuer"
+	// ReasonAuthorityExpired the credential's issuer did not have authority to issue the credential when it was issued
+	ReasonAuthorityExpired Reason = "authority_expired"
+	// ReasonRevoked the credential has been revoked
+	ReasonRevoked Reason = "revoked"
+	// ReasonExpired the credential's validity period has ended
+	ReasonExpired Reason = "expired"
+	// ReasonNotYetValid the credential's validity period has not started
+	ReasonNotYetValid Reason = "not_yet_valid"
+	// ReasonMissingBiometricAnchor the holder has no biometric anchor to link biometric credentials to
+	ReasonMissingBiometricAnchor Reason = "missing_biometric_anchor"
+	// ReasonRejected the credential was rejected by the graph for a reason that could not be determined
+	ReasonRejected Reason = "rejected"
+)
+
+// checks recorded as evidence in a verdict
+const (
+	CheckPresentation    = "presentation"
+	CheckSignature       = "signature"
+	CheckIssuerAuthority = "issuer_authority"
+	CheckRevocation      = "revocation"
+	CheckValidity        = "validity"
+	CheckBiometricAnchor = "biometric_anchor"
+	CheckGraph           = "graph"
+)
+
+// Reason a reason code explaining why a credential was rejected
+type Reason string
+
+// Evidence the result of a single check made against a credential
+type Evidence struct {
+	Check  string `json:"check"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"`
+	// Overridden true if the check failed, but did not prevent the graph from accepting the credential,
+	// such as when a credential has expired between the graph being created and the evaluation time
+	Overridden bool `json:"overridden,omitempty"`
+}
+
+// Verdict explains whether a credential was accepted by the graph,
+// along with the chain of evidence the verdict was reached with
+type Verdict struct {
+	Credential     *VerifiableCredential `json:"-"`
+	CredentialHash []byte                `json:"credentialHash"`
+	CredentialType []string              `json:"credentialType"`
+	Issuer         string                `json:"issuer"`
+	Valid          bool                  `json:"valid"`
+	Reasons        []Reason              `json:"reasons,omitempty"`
+	Evidence       []*Evidence           `json:"evidence"`
+}
+
+// Explanation explains the validity of each of a holder's credentials in the graph
+type Explanation struct {
+	Holder          string     `json:"holder"`
+	Evaluated       time.Time  `json:"evaluated"`
+	ValidDocument   bool       `json:"validDocument"`
+	BiometricAnchor []byte     `json:"biometricAnchor,omitempty"`
+	Reasons         []Reason   `json:"reasons,omitempty"`
+	Verdicts        []*Verdict `json:"verdicts"`
+}
+
+// Explain returns a verdict for each credential held by the holder, explaining why it was accepted or rejected.
+// Rejected credentials are only included if the graph was created from a set of presentations by an account
+func (g *Graph) Explain(holder *Address) (*Explanation, error) {
+	return g.ExplainAt(holder, time.Now())
+}
+
+// ExplainAt returns a verdict for each credential held by the holder, with the validity periods of credentials
+// checked at the given evaluation time. Whether a credential is valid is decided by the graph, so checks that
+// fail at the evaluation time but did not prevent the graph from accepting a credential are marked as overridden
+func (g *Graph) ExplainAt(holder *Address, at time.Time) (*Explanation, error) {
+	explanation := &Explanation{
+		Holder:        holder.String(),
+		Evaluated:     at,
+		ValidDocument: g.ValidDocumentFor(holder),
+	}
+
+	anchor, hasAnchor := g.BiometricAnchorHashFor(holder)
+	if hasAnchor {
+		explanation.BiometricAnchor = anchor
+	}
+
+	valid, err := g.ValidCredentialsFor(holder)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := g.RevokedCredentialsFor(holder)
+	if err != nil {
+		return nil, err
+	}
+
+	validHashes, err := credentialHashes(valid)
+	if err != nil {
+		return nil, err
+	}
+
+	revokedHashes, err := credentialHashes(revoked)
+	if err != nil {
+		return nil, err
+	}
+
+	// the holder's credentials, and whether the presentation they were presented in is valid
+	held := make(map[string]bool)
+	var credentials []*VerifiableCredential
+
+	add := func(c *VerifiableCredential, presentationValid bool) error {
+		credentialHash, err := c.CredentialHash()
+		if err != nil {
+			return err
+		}
+
+		key := hex.EncodeToString(credentialHash)
+
+		presented, ok := held[key]
+		if !ok {
+			credentials = append(credentials, c)
+		}
+
+		held[key] = presented || presentationValid
+
+		return nil
+	}
+
+	for _, presentation := range g.presentations {
+		presentationValid := presentation.Validate() == nil
+
+		for _, c := range presentation.Credentials() {
+			if c.CredentialSubject().String() != explanation.Holder {
+				continue
+			}
+
+			err = add(c, presentationValid)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, c := range slices.Concat(valid, revoked) {
+		err = add(c, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, c := range credentials {
+		credentialHash, err := c.CredentialHash()
+		if err != nil {
+			return nil, err
+		}
+
+		key := hex.EncodeToString(credentialHash)
+
+		verdict := &Verdict{
+			Credential:     c,
+			CredentialHash: credentialHash,
+			CredentialType: c.CredentialType(),
+			Issuer:         c.Issuer().String(),
+			Valid:          validHashes[key],
+		}
+
+		verdict.check(CheckPresentation, held[key], ReasonInvalidPresentation, "presented in an unverifiable presentation")
+
+		err = c.Validate()
+		verdict.check(CheckSignature, err == nil, ReasonInvalidSignature, errorDetail(err))
+
+		g.explainAuthority(verdict, c)
+
+		g.explainRevocation(verdict, c, revokedHashes[key])
+
+		validFrom := c.ValidFrom()
+		verdict.check(CheckValidity, !validFrom.After(explanation.Evaluated), ReasonNotYetValid, "valid from "+validFrom.Format(time.RFC3339))
+
+		validUntil := c.ValidUntil()
+		if !validUntil.IsZero() {
+			verdict.check(CheckValidity, !c.IsExpiredAt(explanation.Evaluated), ReasonExpired, "valid until "+validUntil.Format(time.RFC3339))
+		}
+
+		if slices.Contains(verdict.CredentialType, CredentialTypeLivenessAndFacialComparison) {
+			verdict.check(CheckBiometricAnchor, hasAnchor, ReasonMissingBiometricAnchor, "holder has no biometric anchor")
+		}
+
+		if !verdict.Valid && len(verdict.Reasons) == 0 {
+			verdict.check(CheckGraph, false, ReasonRejected, "rejected by the credential graph")
+		}
+
+		if verdict.Valid {
+			// checks that failed did not prevent the graph from accepting the credential
+			verdict.Reasons = nil
+
+			for _, evidence := range verdict.Evidence {
+				evidence.Overridden = !evidence.Passed
+			}
+		}
+
+		explanation.Verdicts = append(explanation.Verdicts, verdict)
+	}
+
+	if !explanation.ValidDocument && !hasAnchor {
+		explanation.Reasons = append(explanation.Reasons, ReasonMissingBiometricAnchor)
+	}
+
+	return explanation, nil
+}
+
+// JSON encodes the explanation as json
+func (e *Explanation) JSON() ([]byte, error) {
+	return json.MarshalIndent(e, "", "  ")
+}
+
+// Graphviz render the explanation to graphviz dot format
+func (e *Explanation) Graphviz() string {
+	var dot bytes.Buffer
+
+	dot.WriteString("digraph explanation {\n")
+	dot.WriteString("  rankdir=LR;\n")
+
+	holderLabel := e.Holder
+	if len(e.Reasons) > 0 {
+		holderLabel += "\n" + joinReasons(e.Reasons)
+	}
+
+	fmt.Fprintf(&dot, "  holder [shape=box, label=%q];\n", holderLabel)
+
+	issuers := make(map[string]string)
+
+	for i, verdict := range e.Verdicts {
+		color := "green"
+		label := strings.Join(verdict.CredentialType, ", ") + "\nvalid"
+
+		if !verdict.Valid {
+			color = "red"
+			label = strings.Join(verdict.CredentialType, ", ") + "\n" + joinReasons(verdict.Reasons)
+		}
+
+		fmt.Fprintf(&dot, "  credential%d [label=%q, color=%s];\n", i, label, color)
+		fmt.Fprintf(&dot, "  holder -> credential%d;\n", i)
+
+		issuer, ok := issuers[verdict.Issuer]
+		if !ok {
+			issuer = fmt.Sprintf("issuer%d", len(issuers))
+			issuers[verdict.Issuer] = issuer
+			fmt.Fprintf(&dot, "  %s [shape=box, label=%q];\n", issuer, verdict.Issuer)
+		}
+
+		for _, evidence := range verdict.Evidence {
+			if evidence.Check != CheckIssuerAuthority {
+				continue
+			}
+
+			style := "solid"
+			if !evidence.Passed {
+				style = "dashed"
+			}
+
+			fmt.Fprintf(&dot, "  credential%d -> %s [label=%q, style=%s];\n", i, issuer, evidence.Detail, style)
+		}
+	}
+
+	dot.WriteString("}\n")
+
+	return dot.String()
+}
+
+// explainAuthority checks the issuer had authority to issue the credential when it was issued
+func (g *Graph) explainAuthority(verdict *Verdict, c *VerifiableCredential) {
+	if g.registry == nil {
+		return
+	}
+
+	issuer := c.Issuer()
+
+	issuedAt := c.ValidFrom()
+	if issuedAt.IsZero() {
+		issuedAt = c.Created()
+	}
+
+	for _, credentialType := range verdict.CredentialType {
+		if g.registry.AuthorityAt(issuer, credentialType, issuedAt) {
+			verdict.check(CheckIssuerAuthority, true, "", "authorized to issue "+credentialType+" at "+issuedAt.Format(time.RFC3339))
+			return
+		}
+	}
+
+	if g.registry.knownAuthority(issuer, verdict.CredentialType) {
+		verdict.check(CheckIssuerAuthority, false, ReasonAuthorityExpired, "not authorized at "+issuedAt.Format(time.RFC3339))
+		return
+	}
+
+	verdict.check(CheckIssuerAuthority, false, ReasonUntrustedIssuer, "not a trusted issuer of "+strings.Join(verdict.CredentialType, ", "))
+}
+
+// explainRevocation checks the credential has not been revoked, including the statement that revoked it
+func (g *Graph) explainRevocation(verdict *Verdict, c *VerifiableCredential, revoked bool) {
+	if !revoked {
+		verdict.check(CheckRevocation, true, "", "")
+		return
+	}
+
+	detail := "revoked"
+
+	proof, ok := g.RevocationProofFor(c)
+	if ok {
+		detail = fmt.Sprintf(
+			"revoked at %s by statement %d from %s",
+			proof.Revoked().Format(time.RFC3339),
+			proof.Sequence(),
+			proof.Issuer().String(),
+		)
+	}
+
+	verdict.check(CheckRevocation, false, ReasonRevoked, detail)
+}
+
+// check records the result of a check, along with the reason the credential was rejected if it failed
+func (v *Verdict) check(check string, passed bool, reason Reason, detail string) {
+	v.Evidence = append(v.Evidence, &Evidence{
+		Check:  check,
+		Passed: passed,
+		Detail: detail,
+	})
+
+	if !passed && !slices.Contains(v.Reasons, reason) {
+		v.Reasons = append(v.Reasons, reason)
+	}
+}
+
+// knownAuthority returns true if the issuer has been granted authority for any of the credential types,
+// either currently or in a grant added to the registry
+func (r *TrustedIssuerRegistry) knownAuthority(issuer *Address, credentialTypes []string) bool {
+	current, err := r.AuthorityFor(issuer)
+	if err == nil && slices.ContainsFunc(current, func(credentialType string) bool {
+		return slices.Contains(credentialTypes, credentialType)
+	}) {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, trusted := range r.trusted.Issuers {
+		if trusted.Issuer != issuer.String() {
+			continue
+		}
+
+		for _, grant := range trusted.Grants {
+			if slices.Contains(credentialTypes, grant.CredentialType) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func credentialHashes(credentials []*VerifiableCredential) (map[string]bool, error) {
+	hashes := make(map[string]bool, len(credentials))
+
+	for _, c := range credentials {
+		credentialHash, err := c.CredentialHash()
+		if err != nil {
+			return nil, err
+		}
+
+		hashes[hex.EncodeToString(credentialHash)] = true
+	}
+
+	return hashes, nil
+}
+
+func joinReasons(reasons []Reason) string {
+	codes := make([]string, len(reasons))
+
+	for i, reason := range reasons {
+		codes[i] = string(reason)
+	}
+
+	return strings.Join(codes, ", ")
+}
+
+func errorDetail(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
@@ -29,6 +29,9 @@ type IdentityRecord interface {
 // Graph a graph of verifiable credentials
 type Graph struct {
 	ptr *C.self_credential_graph
+	// the registry and presentations the graph was created from, used to explain rejected credentials
+	registry      *TrustedIssuerRegistry
+	presentations []*VerifiablePresentation
 }
 
 func newCredentialGraph(ptr *C.self_credential_graph) *Graph {
@@ -49,6 +52,11 @@ func credentialGraphPtr(c *Graph) *C.self_credential_graph {
 	return c.ptr
 }
 
+func credentialGraphWithSources(g *Graph, registry *TrustedIssuerRegistry, presentations []*VerifiablePresentation) {
+	g.registry = registry
+	g.presentations = presentations
+}
+
 // ValidCredentialsFor gets all valid credentials for a given holder
 func (g *Graph) ValidCredentialsFor(holder *Address) ([]*VerifiableCredential, error) {
 	var collection *C.self_collection_verifiable_credential
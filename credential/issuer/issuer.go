@@ -0,0 +1,250 @@
+// Package issuer provides an issuer service that issues credentials from templates,
+// delivers them to holders and keeps a ledger of issued credentials
+package issuer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joinself/self-go-sdk/account"
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/keypair/signing"
+	"github.com/joinself/self-go-sdk/message"
+	"github.com/joinself/self-go-sdk/revocation/registry"
+)
+
+var (
+	// ErrTemplateNotFound returned when issuing a credential with a template that has not been registered
+	ErrTemplateNotFound = errors.New("credential template not found")
+	// ErrMissingClaim returned when a claim required by a template has not been provided
+	ErrMissingClaim = errors.New("credential claim missing")
+)
+
+// ValidityPolicy determines the validity period of issued credentials
+type ValidityPolicy struct {
+	// Delay the period after issuance before the credential becomes valid
+	Delay time.Duration
+	// Duration the period the credential is valid for. zero issues credentials that do not expire
+	Duration time.Duration
+}
+
+// Template describes a type of credential that can be issued
+type Template struct {
+	// CredentialType the type of the credential, such as credential.CredentialTypeEmail
+	CredentialType []string
+	// Required the claims that must be present, as json pointers relative
+	// to the credential subject, such as "/email/emailAddress"
+	Required []string
+	// Validate an optional function that validates the claims before issuing
+	Validate func(claims map[string]interface{}) error
+	// Validity the validity period of issued credentials
+	Validity ValidityPolicy
+}
+
+// Issuer issues credentials from templates, signed by an accounts signing key
+type Issuer struct {
+	account     *account.Account
+	address     *credential.Address
+	signer      *signing.PublicKey
+	templates   map[string]*Template
+	revocations *registry.Registry
+	mu          sync.RWMutex
+	ledger      sync.Mutex
+}
+
+// New creates a new issuer that issues credentials from the given issuer address, signed by the signing key.
+// The signing key must be held by the account
+func New(acc *account.Account, issuerAddress *credential.Address, signer *signing.PublicKey) *Issuer {
+	return &Issuer{
+		account:     acc,
+		address:     issuerAddress,
+		signer:      signer,
+		templates:   make(map[string]*Template),
+		revocations: registry.New(acc, signer),
+	}
+}
+
+// RevocationPolicy sets the policy used to publish revocations of issued credentials
+func (i *Issuer) RevocationPolicy(policy registry.Policy) *Issuer {
+	i.revocations.Close()
+	i.revocations = registry.New(i.account, i.signer, policy)
+
+	return i
+}
+
+// Close stops publishing batched revocations of issued credentials
+func (i *Issuer) Close() {
+	i.revocations.Close()
+}
+
+// Registry returns the revocation registry of credentials issued by the issuer
+func (i *Issuer) Registry() *registry.Registry {
+	return i.revocations
+}
+
+// Register registers a credential template under the given name
+func (i *Issuer) Register(name string, template *Template) *Issuer {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.templates[name] = template
+
+	return i
+}
+
+// Issue validates the claims against a template, then issues a credential about the subject.
+// The issued credential is recorded in the issuers ledger
+func (i *Issuer) Issue(templateName string, subject *credential.Address, claims map[string]interface{}) (*credential.VerifiableCredential, error) {
+	i.mu.RLock()
+	template, ok := i.templates[templateName]
+	i.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, templateName)
+	}
+
+	for _, required := range template.Required {
+		if !hasClaim(claims, required) {
+			return nil, fmt.Errorf("%w: %s", ErrMissingClaim, required)
+		}
+	}
+
+	if template.Validate != nil {
+		err := template.Validate(claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	issuedAt := time.Now()
+	validFrom := issuedAt.Add(template.Validity.Delay)
+
+	builder := credential.NewCredential().
+		CredentialType(template.CredentialType...).
+		CredentialSubject(subject).
+		CredentialSubjectClaims(claims).
+		Issuer(i.address).
+		ValidFrom(validFrom)
+
+	var validUntil time.Time
+
+	if template.Validity.Duration > 0 {
+		validUntil = validFrom.Add(template.Validity.Duration)
+		builder.ValidUntil(validUntil)
+	}
+
+	unverifiedCredential, err := builder.
+		SignWith(i.signer, issuedAt).
+		Finish()
+
+	if err != nil {
+		return nil, err
+	}
+
+	verifiableCredential, err := i.account.CredentialIssue(unverifiedCredential)
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.revocations.Register(verifiableCredential)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialHash, err := verifiableCredential.CredentialHash()
+	if err != nil {
+		return nil, err
+	}
+
+	revocationHash, err := verifiableCredential.RevocationHash()
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.recordStore(&Record{
+		CredentialHash: credentialHash,
+		RevocationHash: revocationHash,
+		Template:       templateName,
+		CredentialType: verifiableCredential.CredentialType(),
+		Subject:        subject.String(),
+		Issued:         issuedAt,
+		ValidFrom:      validFrom,
+		ValidUntil:     validUntil,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return verifiableCredential, nil
+}
+
+// IssueTo issues a credential about the subject and delivers it to the holder's address
+func (i *Issuer) IssueTo(holderAddress *signing.PublicKey, templateName string, subject *credential.Address, claims map[string]interface{}) (*credential.VerifiableCredential, error) {
+	verifiableCredential, err := i.Issue(templateName, subject, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return verifiableCredential, i.Deliver(holderAddress, verifiableCredential)
+}
+
+// Deliver sends issued credentials to the holder's address, tracking the
+// exchange and recording the delivery in the issuers ledger
+func (i *Issuer) Deliver(holderAddress *signing.PublicKey, credentials ...*credential.VerifiableCredential) error {
+	content, err := message.NewCredential().
+		VerifiableCredential(credentials...).
+		Finish()
+
+	if err != nil {
+		return err
+	}
+
+	err = i.account.MessageSend(holderAddress, content)
+	if err != nil {
+		return err
+	}
+
+	for _, verifiableCredential := range credentials {
+		err = i.account.CredentialExchangeTrack(holderAddress, verifiableCredential, credential.NoLicense)
+		if err != nil {
+			return err
+		}
+
+		credentialHash, err := verifiableCredential.CredentialHash()
+		if err != nil {
+			return err
+		}
+
+		err = i.recordDelivered(credentialHash, holderAddress)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasClaim returns true if the claims contain a value at the json pointer
+func hasClaim(claims map[string]interface{}, pointer string) bool {
+	var current interface{} = claims
+
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		current, ok = object[token]
+		if !ok {
+			return false
+		}
+	}
+
+	return current != nil
+}
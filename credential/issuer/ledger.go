@@ -0,0 +1,132 @@
+package issuer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/joinself/self-go-sdk/keypair/signing"
+)
+
+const ledgerKeyPrefix = "issuer/ledger/"
+
+// Record a record of an issued credential
+type Record struct {
+	CredentialHash []byte    `json:"credentialHash"`
+	RevocationHash []byte    `json:"revocationHash"`
+	Template       string    `json:"template"`
+	CredentialType []string  `json:"credentialType"`
+	Subject        string    `json:"subject"`
+	Holder         string    `json:"holder,omitempty"`
+	Issued         time.Time `json:"issued"`
+	ValidFrom      time.Time `json:"validFrom"`
+	ValidUntil     time.Time `json:"validUntil"`
+	Delivered      time.Time `json:"delivered"`
+	Revoked        time.Time `json:"revoked"`
+	RenewedBy      []byte    `json:"renewedBy,omitempty"`
+}
+
+// IsRevoked returns true if the credential's revocation has been published
+func (r *Record) IsRevoked() bool {
+	return !r.Revoked.IsZero()
+}
+
+// HolderAddress returns the address the credential was delivered to, or nil if it has not been delivered
+func (r *Record) HolderAddress() *signing.PublicKey {
+	if r.Holder == "" {
+		return nil
+	}
+
+	return signing.FromAddress(r.Holder)
+}
+
+// Ledger returns the records of all credentials issued by the issuer
+func (i *Issuer) Ledger() ([]*Record, error) {
+	prefix := i.ledgerPrefix()
+
+	keys, err := i.account.ValueKeys(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*Record, 0, len(keys))
+
+	for _, key := range keys {
+		credentialHash, err := hex.DecodeString(strings.TrimPrefix(key, prefix))
+		if err != nil {
+			continue
+		}
+
+		record, err := i.Record(credentialHash)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// Record returns the record of an issued credential by it's credential hash
+func (i *Issuer) Record(credentialHash []byte) (*Record, error) {
+	encodedRecord, err := i.account.ValueLookup(i.ledgerPrefix() + hex.EncodeToString(credentialHash))
+	if err != nil {
+		return nil, err
+	}
+
+	var record Record
+
+	err = json.Unmarshal(encodedRecord, &record)
+	if err != nil {
+		return nil, err
+	}
+
+	if !record.IsRevoked() {
+		// revocations that were batched or awaiting co-signatures may have since been published
+		revokedAt, revoked, err := i.revocations.RevokedAt(credentialHash)
+		if err == nil && revoked {
+			record.Revoked = revokedAt
+		}
+	}
+
+	return &record, nil
+}
+
+func (i *Issuer) recordStore(record *Record) error {
+	i.ledger.Lock()
+	defer i.ledger.Unlock()
+
+	return i.recordUpdate(record)
+}
+
+func (i *Issuer) recordDelivered(credentialHash []byte, holderAddress *signing.PublicKey) error {
+	i.ledger.Lock()
+	defer i.ledger.Unlock()
+
+	record, err := i.Record(credentialHash)
+	if err != nil {
+		return err
+	}
+
+	record.Holder = holderAddress.String()
+	record.Delivered = time.Now()
+
+	return i.recordUpdate(record)
+}
+
+func (i *Issuer) recordUpdate(record *Record) error {
+	encodedRecord, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return i.account.ValueStore(i.ledgerPrefix()+hex.EncodeToString(record.CredentialHash), encodedRecord)
+}
+
+// ledgerPrefix the key prefix of the records of credentials issued by the issuer,
+// so issuers sharing an account keep separate ledgers
+func (i *Issuer) ledgerPrefix() string {
+	return ledgerKeyPrefix + i.address.Address().String() + "/"
+}
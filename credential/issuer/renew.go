@@ -0,0 +1,147 @@
+package issuer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/revocation/registry"
+)
+
+var (
+	// ErrNotIssued returned when renewing or revoking a credential that is not in the issuers ledger
+	ErrNotIssued = errors.New("credential was not issued by this issuer")
+	// ErrRevoked returned when renewing a credential that has been revoked
+	ErrRevoked = errors.New("credential has been revoked")
+)
+
+// Renew re-issues a credential with the same template, subject and claims, with a new validity period.
+// If the original credential was delivered, the renewed credential is delivered to the same holder.
+// The original credential is revoked once the renewed credential has been issued. Credentials that
+// have been revoked, or are awaiting revocation, cannot be renewed
+func (i *Issuer) Renew(verifiableCredential *credential.VerifiableCredential) (*credential.VerifiableCredential, error) {
+	credentialHash, err := verifiableCredential.CredentialHash()
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := i.Record(credentialHash)
+	if err != nil {
+		return nil, ErrNotIssued
+	}
+
+	if i.revoking(record) {
+		return nil, ErrRevoked
+	}
+
+	claims, err := verifiableCredential.CredentialSubjectClaims()
+	if err != nil {
+		return nil, err
+	}
+
+	// the subject's id is not a claim
+	delete(claims, "id")
+
+	renewedCredential, err := i.Issue(record.Template, verifiableCredential.CredentialSubject(), claims)
+	if err != nil {
+		return nil, err
+	}
+
+	renewedHash, err := renewedCredential.CredentialHash()
+	if err != nil {
+		return nil, err
+	}
+
+	holderAddress := record.HolderAddress()
+
+	if holderAddress != nil {
+		err = i.Deliver(holderAddress, renewedCredential)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = i.revoke(record, renewedHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return renewedCredential, nil
+}
+
+// Revoke revokes an issued credential by it's credential hash. The revocation is published
+// immediately, unless the issuers revocation policy batches revocations or requires co-signatures.
+// The credential's record is marked as revoked once the revocation has been published. Statements
+// awaiting co-signatures can be found with Registry().Awaiting
+func (i *Issuer) Revoke(credentialHash []byte) error {
+	record, err := i.Record(credentialHash)
+	if err != nil {
+		return ErrNotIssued
+	}
+
+	return i.revoke(record, nil)
+}
+
+func (i *Issuer) revoke(record *Record, renewedBy []byte) error {
+	i.ledger.Lock()
+	defer i.ledger.Unlock()
+
+	// the record may have been revoked since it was read
+	current, err := i.Record(record.CredentialHash)
+	if err == nil {
+		record = current
+	}
+
+	if record.IsRevoked() {
+		// keep the time the credential was first revoked
+		if renewedBy == nil || record.RenewedBy != nil {
+			return nil
+		}
+
+		record.RenewedBy = renewedBy
+
+		return i.recordUpdate(record)
+	}
+
+	// the registry keeps the time of the first revocation if the credential is already awaiting revocation
+	err = i.revocations.Revoke(record.CredentialHash, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// batched revocations are published by the registry on it's own schedule
+	if i.revocations.Policy().BatchInterval == 0 {
+		_, err = i.revocations.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	// revocations awaiting co-signatures or the next batch are
+	// marked on the record when it is read after being published
+	revokedAt, revoked, err := i.revocations.RevokedAt(record.CredentialHash)
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		record.Revoked = revokedAt
+	}
+
+	if renewedBy != nil {
+		record.RenewedBy = renewedBy
+	}
+
+	return i.recordUpdate(record)
+}
+
+// revoking returns true if the credential has been revoked, or is awaiting revocation
+func (i *Issuer) revoking(record *Record) bool {
+	if record.IsRevoked() {
+		return true
+	}
+
+	entry, err := i.revocations.Entry(record.CredentialHash)
+
+	return err == nil && entry.State != registry.StateActive
+}
@@ -1,8 +1,65 @@
 package credential
 
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+)
+
 var (
 	NoLicense *License = nil
+	// ErrNotSharingAgreement returned when referencing a credential that is not a sharing agreement
+	ErrNotSharingAgreement = errors.New("credential is not a sharing agreement")
 )
 
+// License describes the terms under which a credential is shared
 type License struct {
+	// Purpose the purpose the credential is shared for
+	Purpose string `json:"purpose"`
+	// Retention how long the recipient may retain the credential for. zero places no limit on retention
+	Retention time.Duration `json:"retention,omitempty"`
+	// OnwardSharing true if the recipient may share the credential with others
+	OnwardSharing bool `json:"onwardSharing,omitempty"`
+	// Jurisdiction the jurisdiction governing the use of the credential, such as an ISO 3166 country code
+	Jurisdiction string `json:"jurisdiction,omitempty"`
+	// Agreement the credential hash of the sharing agreement credential the license is issued under
+	Agreement []byte `json:"agreement,omitempty"`
+}
+
+// DecodeLicense decodes a license
+func DecodeLicense(encodedLicense []byte) (*License, error) {
+	var license License
+	return &license, json.Unmarshal(encodedLicense, &license)
+}
+
+// ReferenceAgreement references the sharing agreement credential the license is issued under
+func (l *License) ReferenceAgreement(agreement *VerifiableCredential) error {
+	if !slices.Contains(agreement.CredentialType(), CredentialTypeSharingAgreement) {
+		return ErrNotSharingAgreement
+	}
+
+	credentialHash, err := agreement.CredentialHash()
+	if err != nil {
+		return err
+	}
+
+	l.Agreement = credentialHash
+
+	return nil
+}
+
+// RetentionExpiresAt returns the time a credential shared at a given time must no longer be retained.
+// Returns the zero time if the license places no limit on retention
+func (l *License) RetentionExpiresAt(sharedAt time.Time) time.Time {
+	if l == nil || l.Retention < 1 {
+		return time.Time{}
+	}
+
+	return sharedAt.Add(l.Retention)
+}
+
+// Encode encodes the license
+func (l *License) Encode() ([]byte, error) {
+	return json.Marshal(l)
 }
@@ -0,0 +1,456 @@
+package credential
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrMissingClaim returned when unmarshaling claims into a struct with a required field that is not present in the credential
+var ErrMissingClaim = errors.New("credential claim missing")
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// claimTag the parsed options of a self struct tag
+type claimTag struct {
+	tokens   []string
+	optional bool
+	date     bool
+}
+
+// MarshalClaims encodes a struct as the claims of a credential subject. Fields are mapped to
+// claims with the self struct tag, which holds the json pointer of the claim relative to the
+// credential subject, so a field tagged `self:"employee/id"` can be matched by predicates on
+// the field /credentialSubject/employee/id.
+//
+// Fields that hold a struct are nested under the field's pointer, and untagged embedded structs
+// are flattened into their parent. Supported field types are strings, booleans, integers, floats,
+// time.Time, []byte, which is hex encoded, and slices of those types. Times are encoded as date
+// times, or as full dates with the date option, such as `self:"person/dateOfBirth,date"`.
+//
+// Pointer fields and fields with the optional option, such as `self:"employee/manager,optional"`,
+// are optional. Optional fields that are nil or hold their zero value are omitted
+func MarshalClaims(v any) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("credential: cannot marshal claims from %T, value must be a struct", v)
+	}
+
+	claims := make(map[string]interface{})
+
+	return claims, marshalStruct(rv, nil, claims)
+}
+
+// UnmarshalClaims decodes the claims of a credential's subject into the struct pointed to by v,
+// using the self struct tags described by MarshalClaims. Returns ErrMissingClaim if a field that
+// is not optional has no claim, and ErrInvalidClaim if a claim cannot be decoded to it's field's type
+func UnmarshalClaims(vc *VerifiableCredential, v any) error {
+	claims, err := vc.CredentialSubjectClaims()
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("credential: cannot unmarshal claims into %T, value must be a pointer to a struct", v)
+	}
+
+	return unmarshalStruct(rv.Elem(), nil, claims)
+}
+
+func marshalStruct(rv reflect.Value, prefix []string, claims map[string]interface{}) error {
+	t := rv.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := rv.Field(i)
+
+		tag, tagged := parseClaimTag(sf, prefix)
+		if tag == nil {
+			continue
+		}
+
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				continue
+			}
+
+			fv = fv.Elem()
+		}
+
+		if !tagged {
+			// untagged embedded struct
+			err := marshalStruct(fv, prefix, claims)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if nestedClaims(fv.Type()) {
+			err := marshalStruct(fv, tag.tokens, claims)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if tag.optional && fv.IsZero() {
+			continue
+		}
+
+		value, err := encodeClaim(fv, tag, claimPointer(tag.tokens))
+		if err != nil {
+			return err
+		}
+
+		err = setClaim(claims, tag.tokens, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unmarshalStruct(rv reflect.Value, prefix []string, claims map[string]interface{}) error {
+	t := rv.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := rv.Field(i)
+
+		tag, tagged := parseClaimTag(sf, prefix)
+		if tag == nil {
+			continue
+		}
+
+		if !tagged {
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					if !fv.CanSet() {
+						// pointers to unexported embedded structs cannot be allocated
+						continue
+					}
+
+					fv.Set(reflect.New(sf.Type.Elem()))
+				}
+
+				fv = fv.Elem()
+			}
+
+			err := unmarshalStruct(fv, prefix, claims)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		pointer := claimPointer(tag.tokens)
+		value, found := lookupClaim(claims, tag.tokens)
+
+		if !found {
+			if tag.optional || sf.Type.Kind() == reflect.Pointer {
+				continue
+			}
+
+			if !nestedClaims(sf.Type) {
+				return fmt.Errorf("%w: %s", ErrMissingClaim, pointer)
+			}
+		}
+
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				fv.Set(reflect.New(sf.Type.Elem()))
+			}
+
+			fv = fv.Elem()
+		}
+
+		if nestedClaims(fv.Type()) {
+			if found {
+				if _, ok := value.(map[string]interface{}); !ok {
+					return fmt.Errorf("%w: %s must be an object", ErrInvalidClaim, pointer)
+				}
+			}
+
+			err := unmarshalStruct(fv, tag.tokens, claims)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		err := decodeClaim(fv, value, pointer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseClaimTag parses the self tag of a field. Returns nil if the field is not mapped to a claim,
+// or false if the field is an untagged embedded struct whose fields are mapped to the parent's claims
+func parseClaimTag(sf reflect.StructField, prefix []string) (*claimTag, bool) {
+	value, ok := sf.Tag.Lookup("self")
+
+	if !ok {
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if sf.Anonymous && nestedClaims(ft) {
+			return &claimTag{tokens: prefix}, false
+		}
+
+		return nil, false
+	}
+
+	if value == "-" || !sf.IsExported() {
+		return nil, false
+	}
+
+	path, options, _ := strings.Cut(value, ",")
+
+	tag := &claimTag{
+		tokens: append([]string{}, prefix...),
+	}
+
+	for _, token := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		// unescape json pointer tokens
+		token = strings.ReplaceAll(token, "~1", "/")
+		token = strings.ReplaceAll(token, "~0", "~")
+		tag.tokens = append(tag.tokens, token)
+	}
+
+	for _, option := range strings.Split(options, ",") {
+		switch option {
+		case "optional":
+			tag.optional = true
+		case "date":
+			tag.date = true
+		}
+	}
+
+	return tag, true
+}
+
+func encodeClaim(v reflect.Value, tag *claimTag, pointer string) (interface{}, error) {
+	if v.Type() == timeType {
+		if tag.date {
+			return v.Interface().(time.Time).Format(time.DateOnly), nil
+		}
+
+		return DateTime(v.Interface().(time.Time)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return hex.EncodeToString(v.Bytes()), nil
+		}
+
+		values := make([]interface{}, v.Len())
+
+		for i := range values {
+			value, err := encodeClaim(v.Index(i), tag, fmt.Sprintf("%s/%d", pointer, i))
+			if err != nil {
+				return nil, err
+			}
+
+			values[i] = value
+		}
+
+		return values, nil
+	default:
+		return nil, fmt.Errorf("credential: cannot marshal claim %s of type %s", pointer, v.Type())
+	}
+}
+
+func decodeClaim(v reflect.Value, value interface{}, pointer string) error {
+	invalid := func(expected string) error {
+		return fmt.Errorf("%w: %s must be %s", ErrInvalidClaim, pointer, expected)
+	}
+
+	if v.Type() == timeType {
+		s, ok := value.(string)
+		if !ok {
+			return invalid("a date or date time")
+		}
+
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			t, err := time.Parse(layout, s)
+			if err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+
+		return invalid("a date or date time")
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return invalid("a string")
+		}
+
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return invalid("a boolean")
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 || v.OverflowInt(int64(n)) {
+			return invalid("an integer in the range of " + v.Type().String())
+		}
+
+		v.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) || n < 0 || n >= math.MaxUint64 || v.OverflowUint(uint64(n)) {
+			return invalid("an integer in the range of " + v.Type().String())
+		}
+
+		v.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		n, ok := value.(float64)
+		if !ok || v.OverflowFloat(n) {
+			return invalid("a number in the range of " + v.Type().String())
+		}
+
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			s, ok := value.(string)
+			if !ok {
+				return invalid("a hex encoded string")
+			}
+
+			b, err := hex.DecodeString(s)
+			if err != nil {
+				return invalid("a hex encoded string")
+			}
+
+			v.SetBytes(b)
+
+			return nil
+		}
+
+		values, ok := value.([]interface{})
+		if !ok {
+			return invalid("an array")
+		}
+
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+
+		for i, element := range values {
+			err := decodeClaim(slice.Index(i), element, fmt.Sprintf("%s/%d", pointer, i))
+			if err != nil {
+				return err
+			}
+		}
+
+		v.Set(slice)
+	default:
+		return fmt.Errorf("credential: cannot unmarshal claim %s into type %s", pointer, v.Type())
+	}
+
+	return nil
+}
+
+// nestedClaims returns true if fields of the type are mapped to nested claims
+func nestedClaims(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// setClaim sets a claim, creating the objects it is nested in
+func setClaim(claims map[string]interface{}, tokens []string, value interface{}) error {
+	object := claims
+
+	for i, token := range tokens[:len(tokens)-1] {
+		switch nested := object[token].(type) {
+		case map[string]interface{}:
+			object = nested
+		case nil:
+			next := make(map[string]interface{})
+			object[token] = next
+			object = next
+		default:
+			return fmt.Errorf("credential: claim %s is not an object", claimPointer(tokens[:i+1]))
+		}
+	}
+
+	key := tokens[len(tokens)-1]
+
+	if _, exists := object[key]; exists {
+		return fmt.Errorf("credential: claim %s is set by more than one field", claimPointer(tokens))
+	}
+
+	object[key] = value
+
+	return nil
+}
+
+// lookupClaim returns the claim at the given path. Claims with a null value are treated as missing
+func lookupClaim(claims map[string]interface{}, tokens []string) (interface{}, bool) {
+	var value interface{} = claims
+
+	for _, token := range tokens {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		value, ok = object[token]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return value, value != nil
+}
+
+// claimPointer formats the json pointer of a claim, relative to the credential subject
+func claimPointer(tokens []string) string {
+	var b strings.Builder
+
+	for _, token := range tokens {
+		token = strings.ReplaceAll(token, "~", "~0")
+		token = strings.ReplaceAll(token, "/", "~1")
+		b.WriteString("/" + token)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,299 @@
+package predicate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OperatorAnd                 Operator = "and"
+	OperatorOr                  Operator = "or"
+	OperatorEquals              Operator = "=="
+	OperatorNotEquals           Operator = "!="
+	OperatorGreaterThan         Operator = ">"
+	OperatorGreaterThanOrEquals Operator = ">="
+	OperatorLessThan            Operator = "<"
+	OperatorLessThanOrEquals    Operator = "<="
+	OperatorContains            Operator = "contains"
+	OperatorNotContains         Operator = "not contains"
+	OperatorOneOf               Operator = "in"
+	OperatorNotOneOf            Operator = "not in"
+	OperatorEmpty               Operator = "empty"
+	OperatorNotEmpty            Operator = "not empty"
+)
+
+var (
+	// ErrNoExpression returned when encoding a predicate or tree that was not created by this package, such as one received in a request
+	ErrNoExpression = errors.New("predicate has no expression")
+)
+
+// Operator the operator of an expression
+type Operator string
+
+// Expression a serializable representation of a predicate. Expressions are either a comparison of
+// a credential field against one or more values, or an and/or of other expressions
+type Expression struct {
+	Operator Operator
+	// Field the RFC 6901 JSON Pointer of the field compared by the expression
+	Field string
+	// Values the values the field is compared against. comparisons other than
+	// one of and not one of have a single value, where empty comparisons have none.
+	// relative comparisons have a number of years or a duration
+	Values []string
+	// Operands the expressions joined by an and/or expression
+	Operands []*Expression
+}
+
+type encodedExpression struct {
+	And      []*Expression `json:"and,omitempty"`
+	Or       []*Expression `json:"or,omitempty"`
+	Operator Operator      `json:"op,omitempty"`
+	Field    string        `json:"field,omitempty"`
+	Value    *string       `json:"value,omitempty"`
+	Values   []string      `json:"values,omitempty"`
+}
+
+// DecodeExpression decodes a json encoded expression
+func DecodeExpression(encodedExpression []byte) (*Expression, error) {
+	var e Expression
+	return &e, json.Unmarshal(encodedExpression, &e)
+}
+
+// Predicate builds the predicate described by the expression
+func (e *Expression) Predicate() (*Predicate, error) {
+	err := e.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return e.predicate(time.Now()), nil
+}
+
+// Tree builds a predicate tree from the expression
+func (e *Expression) Tree() (*Tree, error) {
+	p, err := e.Predicate()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTree(p), nil
+}
+
+// String formats the expression in the predicate language accepted by Parse
+func (e *Expression) String() string {
+	var b strings.Builder
+	e.format(&b, OperatorOr)
+	return b.String()
+}
+
+// MarshalJSON encodes the expression as json
+func (e *Expression) MarshalJSON() ([]byte, error) {
+	encoded := encodedExpression{}
+
+	switch e.Operator {
+	case OperatorAnd:
+		encoded.And = e.Operands
+	case OperatorOr:
+		encoded.Or = e.Operands
+	case OperatorOneOf, OperatorNotOneOf:
+		encoded.Operator = e.Operator
+		encoded.Field = e.Field
+		encoded.Values = e.Values
+		if encoded.Values == nil {
+			encoded.Values = []string{}
+		}
+	default:
+		encoded.Operator = e.Operator
+		encoded.Field = e.Field
+		if len(e.Values) > 0 {
+			encoded.Value = &e.Values[0]
+		}
+	}
+
+	return json.Marshal(&encoded)
+}
+
+// UnmarshalJSON decodes a json encoded expression
+func (e *Expression) UnmarshalJSON(data []byte) error {
+	var encoded encodedExpression
+
+	err := json.Unmarshal(data, &encoded)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case encoded.And != nil:
+		*e = Expression{Operator: OperatorAnd, Operands: encoded.And}
+	case encoded.Or != nil:
+		*e = Expression{Operator: OperatorOr, Operands: encoded.Or}
+	default:
+		*e = Expression{Operator: encoded.Operator, Field: encoded.Field, Values: encoded.Values}
+		if encoded.Value != nil {
+			e.Values = []string{*encoded.Value}
+		}
+	}
+
+	return e.validate()
+}
+
+// validate checks the expression and all of it's operands are well formed
+func (e *Expression) validate() error {
+	switch e.Operator {
+	case OperatorAnd, OperatorOr:
+		if len(e.Operands) == 0 {
+			return fmt.Errorf("predicate: %s expression has no operands", e.Operator)
+		}
+
+		for _, operand := range e.Operands {
+			if operand == nil {
+				return fmt.Errorf("predicate: %s expression has an empty operand", e.Operator)
+			}
+
+			err := operand.validate()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case OperatorEquals, OperatorNotEquals, OperatorGreaterThan, OperatorGreaterThanOrEquals,
+		OperatorLessThan, OperatorLessThanOrEquals, OperatorContains, OperatorNotContains:
+		if len(e.Values) != 1 {
+			return fmt.Errorf("predicate: %s expression on %s requires a single value", e.Operator, e.Field)
+		}
+	case OperatorAgeAtLeast, OperatorWithinLast, OperatorExpiresAfter:
+		err := e.validateRelative()
+		if err != nil {
+			return err
+		}
+	case OperatorOneOf, OperatorNotOneOf:
+	case OperatorEmpty, OperatorNotEmpty:
+		if len(e.Values) != 0 {
+			return fmt.Errorf("predicate: %s expression on %s does not take a value", e.Operator, e.Field)
+		}
+	default:
+		return fmt.Errorf("predicate: unknown operator %q", e.Operator)
+	}
+
+	if !strings.HasPrefix(e.Field, "/") {
+		return fmt.Errorf("predicate: field %q is not a json pointer", e.Field)
+	}
+
+	return nil
+}
+
+func (e *Expression) predicate(now time.Time) *Predicate {
+	switch e.Operator {
+	case OperatorAnd, OperatorOr:
+		p := e.Operands[0].predicate(now)
+
+		for _, operand := range e.Operands[1:] {
+			if e.Operator == OperatorAnd {
+				p = p.And(operand.predicate(now))
+			} else {
+				p = p.Or(operand.predicate(now))
+			}
+		}
+
+		return p
+	case OperatorAgeAtLeast, OperatorWithinLast, OperatorExpiresAfter:
+		return e.resolve(now)
+	case OperatorEquals:
+		return Equals(e.Field, e.Values[0])
+	case OperatorNotEquals:
+		return NotEquals(e.Field, e.Values[0])
+	case OperatorGreaterThan:
+		return GreaterThan(e.Field, e.Values[0])
+	case OperatorGreaterThanOrEquals:
+		return GreaterThanOrEquals(e.Field, e.Values[0])
+	case OperatorLessThan:
+		return LessThan(e.Field, e.Values[0])
+	case OperatorLessThanOrEquals:
+		return LessThanOrEquals(e.Field, e.Values[0])
+	case OperatorContains:
+		return Contains(e.Field, e.Values[0])
+	case OperatorNotContains:
+		return NotContains(e.Field, e.Values[0])
+	case OperatorOneOf:
+		return OneOf(e.Field, e.Values)
+	case OperatorNotOneOf:
+		return NotOneOf(e.Field, e.Values)
+	case OperatorEmpty:
+		return Empty(e.Field)
+	default:
+		return NotEmpty(e.Field)
+	}
+}
+
+// format writes the expression, wrapping it in parentheses if it binds less tightly than it's parent
+func (e *Expression) format(b *strings.Builder, parent Operator) {
+	switch e.Operator {
+	case OperatorAnd, OperatorOr:
+		parenthesize := e.Operator == OperatorOr && parent == OperatorAnd
+
+		if parenthesize {
+			b.WriteString("(")
+		}
+
+		for i, operand := range e.Operands {
+			if i > 0 {
+				b.WriteString(" " + string(e.Operator) + " ")
+			}
+
+			operand.format(b, e.Operator)
+		}
+
+		if parenthesize {
+			b.WriteString(")")
+		}
+	case OperatorOneOf, OperatorNotOneOf:
+		values := make([]string, len(e.Values))
+
+		for i, value := range e.Values {
+			values[i] = strconv.Quote(value)
+		}
+
+		fmt.Fprintf(b, "%s %s [%s]", formatField(e.Field), e.Operator, strings.Join(values, ", "))
+	case OperatorEmpty, OperatorNotEmpty:
+		fmt.Fprintf(b, "%s is %s", formatField(e.Field), e.Operator)
+	case OperatorAgeAtLeast, OperatorWithinLast, OperatorExpiresAfter:
+		fmt.Fprintf(b, "%s %s %s", formatField(e.Field), e.Operator, e.Values[0])
+	default:
+		fmt.Fprintf(b, "%s %s %s", formatField(e.Field), e.Operator, strconv.Quote(e.Values[0]))
+	}
+}
+
+// formatField quotes fields that contain characters that would otherwise end the field
+func formatField(field string) string {
+	if field == "" || strings.ContainsAny(field, fieldDelimiters) {
+		return strconv.Quote(field)
+	}
+
+	return field
+}
+
+// join joins two expressions, flattening operands that use the same operator
+func join(operator Operator, left, right *Expression) *Expression {
+	if left == nil || right == nil {
+		return nil
+	}
+
+	joined := &Expression{
+		Operator: operator,
+	}
+
+	for _, operand := range []*Expression{left, right} {
+		if operand.Operator == operator {
+			joined.Operands = append(joined.Operands, operand.Operands...)
+		} else {
+			joined.Operands = append(joined.Operands, operand)
+		}
+	}
+
+	return joined
+}
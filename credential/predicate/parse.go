@@ -0,0 +1,498 @@
+package predicate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// characters that end an unquoted field or value
+const fieldDelimiters = " \t\r\n()[],\"!=<>"
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLeftParen
+	tokenRightParen
+	tokenLeftBracket
+	tokenRightBracket
+	tokenComma
+	tokenField
+	tokenString
+	tokenValue
+	tokenComparison
+	tokenKeyword
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+// SyntaxError describes where parsing a predicate failed
+type SyntaxError struct {
+	// Offset the byte offset of the error
+	Offset int
+	// Line and Column the 1-based position of the error
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("predicate: %d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+// Parse parses an expression written in the predicate language. Comparisons take the form of a
+// json pointer field, an operator and a quoted value, and can be joined with and, or and parentheses:
+//
+//	/type contains "EmailCredential" and /credentialSubject/email/emailAddress contains "@acme.com"
+//
+// Supported operators are ==, !=, >, >=, <, <=, contains, not contains, in [...], not in [...],
+// is empty and is not empty. Dates can be compared relative to the current time with
+// age at least <years>, within last <duration> and expires after <duration>, where durations
+// are either go durations such as 720h, or a number of days such as 30d. and binds more tightly than or
+func Parse(text string) (*Expression, error) {
+	p := &parser{
+		text: text,
+	}
+
+	err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.token.kind != tokenEOF {
+		return nil, p.errorf(p.token.offset, "unexpected %s, expected and, or or end of expression", p.token.describe())
+	}
+
+	return e, nil
+}
+
+// ParsePredicate parses an expression written in the predicate language and builds it's predicate
+func ParsePredicate(text string) (*Predicate, error) {
+	e, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Predicate()
+}
+
+// ParseTree parses an expression written in the predicate language and builds it's predicate tree
+func ParseTree(text string) (*Tree, error) {
+	e, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Tree()
+}
+
+type parser struct {
+	text   string
+	offset int
+	token  token
+}
+
+func (p *parser) or() (*Expression, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("or") {
+		err = p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		left = join(OperatorOr, left, right)
+	}
+
+	return left, nil
+}
+
+func (p *parser) and() (*Expression, error) {
+	left, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("and") {
+		err = p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = join(OperatorAnd, left, right)
+	}
+
+	return left, nil
+}
+
+func (p *parser) primary() (*Expression, error) {
+	switch p.token.kind {
+	case tokenLeftParen:
+		err := p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.token.kind != tokenRightParen {
+			return nil, p.errorf(p.token.offset, "unexpected %s, expected )", p.token.describe())
+		}
+
+		return e, p.next()
+	case tokenField:
+		return p.comparison(p.token.text)
+	case tokenString:
+		if !strings.HasPrefix(p.token.text, "/") {
+			return nil, p.errorf(p.token.offset, "field %q is not a json pointer", p.token.text)
+		}
+
+		return p.comparison(p.token.text)
+	default:
+		return nil, p.errorf(p.token.offset, "unexpected %s, expected a field or (", p.token.describe())
+	}
+}
+
+func (p *parser) comparison(field string) (*Expression, error) {
+	err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Expression{
+		Field: field,
+	}
+
+	operatorOffset := p.token.offset
+
+	switch {
+	case p.token.kind == tokenComparison:
+		e.Operator = Operator(p.token.text)
+	case p.keyword("contains"):
+		e.Operator = OperatorContains
+	case p.keyword("in"):
+		e.Operator = OperatorOneOf
+	case p.keyword("not"):
+		err = p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case p.keyword("contains"):
+			e.Operator = OperatorNotContains
+		case p.keyword("in"):
+			e.Operator = OperatorNotOneOf
+		default:
+			return nil, p.errorf(p.token.offset, "unexpected %s, expected contains or in", p.token.describe())
+		}
+	case p.keyword("age"):
+		e.Operator = OperatorAgeAtLeast
+
+		err = p.expect("at", "least")
+		if err != nil {
+			return nil, err
+		}
+	case p.keyword("within"):
+		e.Operator = OperatorWithinLast
+
+		err = p.expect("last")
+		if err != nil {
+			return nil, err
+		}
+	case p.keyword("expires"):
+		e.Operator = OperatorExpiresAfter
+
+		err = p.expect("after")
+		if err != nil {
+			return nil, err
+		}
+	case p.keyword("is"):
+		err = p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		e.Operator = OperatorEmpty
+
+		if p.keyword("not") {
+			e.Operator = OperatorNotEmpty
+
+			err = p.next()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if !p.keyword("empty") {
+			return nil, p.errorf(p.token.offset, "unexpected %s, expected empty", p.token.describe())
+		}
+
+		return e, p.next()
+	default:
+		return nil, p.errorf(operatorOffset, "unexpected %s, expected an operator", p.token.describe())
+	}
+
+	err = p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Operator == OperatorOneOf || e.Operator == OperatorNotOneOf {
+		e.Values, err = p.list()
+		return e, err
+	}
+
+	valueOffset := p.token.offset
+
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	e.Values = []string{value}
+
+	if e.Relative() {
+		err = e.validateRelative()
+		if err != nil {
+			expected := "a duration"
+			if e.Operator == OperatorAgeAtLeast {
+				expected = "a number of years"
+			}
+
+			return nil, p.errorf(valueOffset, "invalid value %q, expected %s", value, expected)
+		}
+	}
+
+	return e, nil
+}
+
+// expect advances past the current keyword, then checks the next tokens are the expected keywords
+func (p *parser) expect(keywords ...string) error {
+	for _, keyword := range keywords {
+		err := p.next()
+		if err != nil {
+			return err
+		}
+
+		if !p.keyword(keyword) {
+			return p.errorf(p.token.offset, "unexpected %s, expected %s", p.token.describe(), keyword)
+		}
+	}
+
+	return nil
+}
+
+func (p *parser) list() ([]string, error) {
+	if p.token.kind != tokenLeftBracket {
+		return nil, p.errorf(p.token.offset, "unexpected %s, expected [", p.token.describe())
+	}
+
+	err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	values := []string{}
+
+	for p.token.kind != tokenRightBracket {
+		if len(values) > 0 {
+			if p.token.kind != tokenComma {
+				return nil, p.errorf(p.token.offset, "unexpected %s, expected , or ]", p.token.describe())
+			}
+
+			err = p.next()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, p.next()
+}
+
+func (p *parser) value() (string, error) {
+	if p.token.kind != tokenString && p.token.kind != tokenValue {
+		return "", p.errorf(p.token.offset, "unexpected %s, expected a value", p.token.describe())
+	}
+
+	value := p.token.text
+
+	return value, p.next()
+}
+
+// keyword returns true if the current token is the given keyword
+func (p *parser) keyword(keyword string) bool {
+	return p.token.kind == tokenKeyword && p.token.text == keyword
+}
+
+// next scans the next token
+func (p *parser) next() error {
+	for p.offset < len(p.text) {
+		r, size := utf8.DecodeRuneInString(p.text[p.offset:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		p.offset += size
+	}
+
+	start := p.offset
+
+	if start >= len(p.text) {
+		p.token = token{kind: tokenEOF, offset: start}
+		return nil
+	}
+
+	c := p.text[start]
+
+	switch c {
+	case '(':
+		p.token = token{kind: tokenLeftParen, text: "(", offset: start}
+		p.offset++
+	case ')':
+		p.token = token{kind: tokenRightParen, text: ")", offset: start}
+		p.offset++
+	case '[':
+		p.token = token{kind: tokenLeftBracket, text: "[", offset: start}
+		p.offset++
+	case ']':
+		p.token = token{kind: tokenRightBracket, text: "]", offset: start}
+		p.offset++
+	case ',':
+		p.token = token{kind: tokenComma, text: ",", offset: start}
+		p.offset++
+	case '"':
+		return p.scanString(start)
+	case '=', '!', '<', '>':
+		end := start + 1
+		if end < len(p.text) && p.text[end] == '=' {
+			end++
+		}
+
+		text := p.text[start:end]
+
+		switch text {
+		case "==", "!=", "<", "<=", ">", ">=":
+		default:
+			return p.errorf(start, "unknown operator %q", text)
+		}
+
+		p.token = token{kind: tokenComparison, text: text, offset: start}
+		p.offset = end
+	default:
+		end := start
+		for end < len(p.text) && !strings.ContainsRune(fieldDelimiters, rune(p.text[end])) {
+			end++
+		}
+
+		text := p.text[start:end]
+		p.offset = end
+
+		switch {
+		case c == '/':
+			p.token = token{kind: tokenField, text: text, offset: start}
+		case isKeyword(strings.ToLower(text)):
+			p.token = token{kind: tokenKeyword, text: strings.ToLower(text), offset: start}
+		default:
+			p.token = token{kind: tokenValue, text: text, offset: start}
+		}
+	}
+
+	return nil
+}
+
+// scanString scans a double quoted string, which uses the same escapes as go string literals
+func (p *parser) scanString(start int) error {
+	end := start + 1
+
+	for end < len(p.text) {
+		switch p.text[end] {
+		case '\\':
+			end += 2
+			continue
+		case '"':
+			value, err := strconv.Unquote(p.text[start : end+1])
+			if err != nil {
+				return p.errorf(start, "invalid string %s", p.text[start:end+1])
+			}
+
+			p.token = token{kind: tokenString, text: value, offset: start}
+			p.offset = end + 1
+
+			return nil
+		case '\n':
+			return p.errorf(end, "newline in string")
+		}
+
+		end++
+	}
+
+	return p.errorf(start, "unterminated string")
+}
+
+func (p *parser) errorf(offset int, format string, args ...interface{}) error {
+	line := 1 + strings.Count(p.text[:offset], "\n")
+	column := 1 + utf8.RuneCountInString(p.text[strings.LastIndex(p.text[:offset], "\n")+1:offset])
+
+	return &SyntaxError{
+		Offset: offset,
+		Line:   line,
+		Column: column,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return "'" + t.text + "'"
+	}
+}
+
+func isKeyword(text string) bool {
+	switch text {
+	case "and", "or", "not", "contains", "in", "is", "empty", "age", "at", "least", "within", "last", "expires", "after":
+		return true
+	default:
+		return false
+	}
+}
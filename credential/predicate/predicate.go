@@ -9,17 +9,21 @@ package predicate
 */
 import "C"
 import (
+	"encoding/json"
 	"runtime"
+	"slices"
 	"unsafe"
 )
 
 type Predicate struct {
-	ptr *C.self_credential_predicate
+	ptr        *C.self_credential_predicate
+	expression *Expression
 }
 
-func newCredentialPredicate(ptr *C.self_credential_predicate) *Predicate {
+func newCredentialPredicate(ptr *C.self_credential_predicate, expression *Expression) *Predicate {
 	p := &Predicate{
-		ptr: ptr,
+		ptr:        ptr,
+		expression: expression,
 	}
 
 	runtime.AddCleanup(p, func(ptr *C.self_credential_predicate) {
@@ -50,6 +54,7 @@ func Equals(field, value string) *Predicate {
 			predicateField,
 			predicateValue,
 		),
+		&Expression{Operator: OperatorEquals, Field: field, Values: []string{value}},
 	)
 }
 
@@ -72,6 +77,7 @@ func NotEquals(field, value string) *Predicate {
 			predicateField,
 			predicateValue,
 		),
+		&Expression{Operator: OperatorNotEquals, Field: field, Values: []string{value}},
 	)
 }
 
@@ -93,6 +99,7 @@ func GreaterThan(field, value string) *Predicate {
 			predicateField,
 			predicateValue,
 		),
+		&Expression{Operator: OperatorGreaterThan, Field: field, Values: []string{value}},
 	)
 }
 
@@ -114,6 +121,7 @@ func GreaterThanOrEquals(field, value string) *Predicate {
 			predicateField,
 			predicateValue,
 		),
+		&Expression{Operator: OperatorGreaterThanOrEquals, Field: field, Values: []string{value}},
 	)
 }
 
@@ -135,6 +143,7 @@ func LessThan(field, value string) *Predicate {
 			predicateField,
 			predicateValue,
 		),
+		&Expression{Operator: OperatorLessThan, Field: field, Values: []string{value}},
 	)
 }
 
@@ -156,6 +165,7 @@ func LessThanOrEquals(field, value string) *Predicate {
 			predicateField,
 			predicateValue,
 		),
+		&Expression{Operator: OperatorLessThanOrEquals, Field: field, Values: []string{value}},
 	)
 }
 
@@ -177,6 +187,7 @@ func Contains(field, value string) *Predicate {
 			predicateField,
 			predicateValue,
 		),
+		&Expression{Operator: OperatorContains, Field: field, Values: []string{value}},
 	)
 }
 
@@ -198,6 +209,7 @@ func NotContains(field, value string) *Predicate {
 			predicateField,
 			predicateValue,
 		),
+		&Expression{Operator: OperatorNotContains, Field: field, Values: []string{value}},
 	)
 }
 
@@ -231,6 +243,7 @@ func OneOf(field string, values []string) *Predicate {
 			predicateField,
 			collection,
 		),
+		&Expression{Operator: OperatorOneOf, Field: field, Values: slices.Clone(values)},
 	)
 }
 
@@ -264,6 +277,7 @@ func NotOneOf(field string, values []string) *Predicate {
 			predicateField,
 			collection,
 		),
+		&Expression{Operator: OperatorNotOneOf, Field: field, Values: slices.Clone(values)},
 	)
 }
 
@@ -282,6 +296,7 @@ func Empty(field string) *Predicate {
 		C.self_credential_predicate_empty(
 			predicateField,
 		),
+		&Expression{Operator: OperatorEmpty, Field: field},
 	)
 }
 
@@ -301,6 +316,7 @@ func NotEmpty(field string) *Predicate {
 
 			predicateField,
 		),
+		&Expression{Operator: OperatorNotEmpty, Field: field},
 	)
 }
 
@@ -311,6 +327,7 @@ func (p *Predicate) And(other *Predicate) *Predicate {
 			p.ptr,
 			other.ptr,
 		),
+		join(OperatorAnd, p.expression, other.expression),
 	)
 }
 
@@ -321,5 +338,40 @@ func (p *Predicate) Or(other *Predicate) *Predicate {
 			p.ptr,
 			other.ptr,
 		),
+		join(OperatorOr, p.expression, other.expression),
 	)
 }
+
+// Expression returns the expression the predicate was built from. Returns nil
+// if the predicate was not built by this package, such as one received in a request
+func (p *Predicate) Expression() *Expression {
+	return p.expression
+}
+
+// String formats the predicate in the predicate language accepted by Parse
+func (p *Predicate) String() string {
+	if p.expression == nil {
+		return ""
+	}
+
+	return p.expression.String()
+}
+
+// Encode encodes the predicate as json
+func (p *Predicate) Encode() ([]byte, error) {
+	if p.expression == nil {
+		return nil, ErrNoExpression
+	}
+
+	return json.Marshal(p.expression)
+}
+
+// DecodePredicate decodes a json encoded predicate
+func DecodePredicate(encodedPredicate []byte) (*Predicate, error) {
+	e, err := DecodeExpression(encodedPredicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Predicate()
+}
@@ -0,0 +1,196 @@
+package predicate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+)
+
+const (
+	OperatorAgeAtLeast   Operator = "age at least"
+	OperatorWithinLast   Operator = "within last"
+	OperatorExpiresAfter Operator = "expires after"
+)
+
+// AgeAtLeast checks if a date of birth field is at least the given number of years ago.
+// The predicate is resolved against the current time when it is created, and trees built
+// from it are resolved again when they are matched, unless they are resolved against
+// another time with ResolveAt.
+//
+// The `field` parameter should be in the form of an RFC 6901 JSON Pointer
+// See: https://datatracker.ietf.org/doc/html/rfc6901
+func AgeAtLeast(field string, years int) *Predicate {
+	return (&Expression{
+		Operator: OperatorAgeAtLeast,
+		Field:    field,
+		Values:   []string{strconv.Itoa(years)},
+	}).predicate(time.Now())
+}
+
+// WithinLast checks if a date field is within the given duration before the current time.
+// The predicate is resolved against the current time when it is created, and trees built
+// from it are resolved again when they are matched, unless they are resolved against
+// another time with ResolveAt.
+//
+// The `field` parameter should be in the form of an RFC 6901 JSON Pointer
+// See: https://datatracker.ietf.org/doc/html/rfc6901
+func WithinLast(field string, duration time.Duration) *Predicate {
+	return (&Expression{
+		Operator: OperatorWithinLast,
+		Field:    field,
+		Values:   []string{duration.String()},
+	}).predicate(time.Now())
+}
+
+// ExpiresAfter checks if a date field, such as a credential's valid until date, is later than the
+// given duration after the current time. The predicate is resolved against the current time when
+// it is created, and trees built from it are resolved again when they are matched, unless they are
+// resolved against another time with ResolveAt.
+//
+// The `field` parameter should be in the form of an RFC 6901 JSON Pointer
+// See: https://datatracker.ietf.org/doc/html/rfc6901
+func ExpiresAfter(field string, duration time.Duration) *Predicate {
+	return (&Expression{
+		Operator: OperatorExpiresAfter,
+		Field:    field,
+		Values:   []string{duration.String()},
+	}).predicate(time.Now())
+}
+
+// ResolveAt resolves predicates that are relative to the current time, such as AgeAtLeast, against
+// the given time. Predicates without relative expressions are returned unchanged
+func (p *Predicate) ResolveAt(now time.Time) *Predicate {
+	if p.expression == nil || !p.expression.Relative() {
+		return p
+	}
+
+	return p.expression.predicate(now)
+}
+
+// ResolveAt resolves predicates that are relative to the current time, such as AgeAtLeast, against
+// the given time. The returned tree stays resolved against that time when it is matched. Trees
+// without relative expressions are returned unchanged
+func (p *Tree) ResolveAt(now time.Time) *Tree {
+	if p.expression == nil || !p.expression.Relative() {
+		return p
+	}
+
+	t := NewTree(p.expression.predicate(now))
+	t.resolvedAt = now
+
+	return t
+}
+
+// current returns the tree resolved against the current time, unless
+// it is not relative or was resolved against another time with ResolveAt
+func (p *Tree) current() *Tree {
+	if !p.resolvedAt.IsZero() || p.expression == nil || !p.expression.Relative() {
+		return p
+	}
+
+	return NewTree(p.expression.predicate(time.Now()))
+}
+
+// Relative returns true if the expression contains comparisons relative to the current time
+func (e *Expression) Relative() bool {
+	switch e.Operator {
+	case OperatorAgeAtLeast, OperatorWithinLast, OperatorExpiresAfter:
+		return true
+	case OperatorAnd, OperatorOr:
+		for _, operand := range e.Operands {
+			if operand.Relative() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// PredicateAt builds the predicate described by the expression,
+// resolving relative comparisons against the given time
+func (e *Expression) PredicateAt(now time.Time) (*Predicate, error) {
+	err := e.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return e.predicate(now), nil
+}
+
+// resolve builds the comparison a relative expression is equivalent to at the given time
+func (e *Expression) resolve(now time.Time) *Predicate {
+	var p *Predicate
+
+	switch e.Operator {
+	case OperatorAgeAtLeast:
+		years, _ := strconv.Atoi(e.Values[0])
+
+		// anyone born at any point on the cutoff date has reached the age
+		cutoff := now.UTC().AddDate(-years, 0, 0)
+
+		if cutoff.Day() != now.UTC().Day() {
+			// on the 29th of february, AddDate normalises years without a leap
+			// day to the 1st of march, where the cutoff should be the 28th
+			cutoff = cutoff.AddDate(0, 0, -cutoff.Day())
+		}
+
+		cutoff = time.Date(cutoff.Year(), cutoff.Month(), cutoff.Day(), 23, 59, 59, 0, time.UTC)
+
+		p = LessThanOrEquals(e.Field, credential.DateTime(cutoff))
+	case OperatorWithinLast:
+		duration, _ := parseDuration(e.Values[0])
+		p = GreaterThanOrEquals(e.Field, credential.DateTime(now.Add(-duration)))
+	default:
+		duration, _ := parseDuration(e.Values[0])
+		p = GreaterThan(e.Field, credential.DateTime(now.Add(duration)))
+	}
+
+	// keep the relative expression, so the predicate can be resolved again
+	p.expression = e
+
+	return p
+}
+
+// validateRelative checks the value of a relative expression
+func (e *Expression) validateRelative() error {
+	if len(e.Values) != 1 {
+		return fmt.Errorf("predicate: %s expression on %s requires a single value", e.Operator, e.Field)
+	}
+
+	if e.Operator == OperatorAgeAtLeast {
+		years, err := strconv.Atoi(e.Values[0])
+		if err != nil || years < 0 {
+			return fmt.Errorf("predicate: %s expression on %s requires a number of years", e.Operator, e.Field)
+		}
+
+		return nil
+	}
+
+	duration, err := parseDuration(e.Values[0])
+	if err != nil {
+		return fmt.Errorf("predicate: %s expression on %s requires a duration: %w", e.Operator, e.Field, err)
+	}
+
+	if duration < 0 {
+		return fmt.Errorf("predicate: %s expression on %s requires a duration that is not negative", e.Operator, e.Field)
+	}
+
+	return nil
+}
+
+// parseDuration parses a duration, which may also be a whole number of days, such as 30d
+func parseDuration(value string) (time.Duration, error) {
+	days, ok := strings.CutSuffix(value, "d")
+	if ok {
+		n, err := strconv.Atoi(days)
+		if err == nil {
+			return time.Duration(n) * time.Hour * 24, nil
+		}
+	}
+
+	return time.ParseDuration(value)
+}
@@ -9,7 +9,9 @@ package predicate
 */
 import "C"
 import (
+	"encoding/json"
 	"runtime"
+	"time"
 
 	"github.com/joinself/self-go-sdk/credential"
 )
@@ -21,7 +23,9 @@ func toVerifiableCredentialCollection(credentials []*credential.VerifiableCreden
 func fromVerifiableCredentialCollection(ptr *C.self_collection_verifiable_credential) []*credential.VerifiableCredential
 
 type Tree struct {
-	ptr *C.self_credential_predicate_tree
+	ptr        *C.self_credential_predicate_tree
+	expression *Expression
+	resolvedAt time.Time
 }
 
 func newCredentialPredicateTree(ptr *C.self_credential_predicate_tree) *Tree {
@@ -43,16 +47,57 @@ func credentialPredicateTreePtr(f *Tree) *C.self_credential_predicate_tree {
 }
 
 func NewTree(predicate *Predicate) *Tree {
-	return newCredentialPredicateTree(
+	t := newCredentialPredicateTree(
 		C.self_credential_predicate_tree_init(
 			predicate.ptr,
 		),
 	)
+
+	t.expression = predicate.expression
+
+	return t
+}
+
+// DecodeTree decodes a json encoded predicate tree
+func DecodeTree(encodedTree []byte) (*Tree, error) {
+	e, err := DecodeExpression(encodedTree)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Tree()
+}
+
+// Expression returns the expression the tree was built from. Returns nil
+// if the tree was not built by this package, such as one received in a request
+func (p *Tree) Expression() *Expression {
+	return p.expression
+}
+
+// String formats the tree in the predicate language accepted by Parse
+func (p *Tree) String() string {
+	if p.expression == nil {
+		return ""
+	}
+
+	return p.expression.String()
+}
+
+// Encode encodes the tree as json, so it can be stored in configuration
+func (p *Tree) Encode() ([]byte, error) {
+	if p.expression == nil {
+		return nil, ErrNoExpression
+	}
+
+	return json.Marshal(p.expression)
 }
 
 // FindOptimalMatch finds the optimal set of credentials that match the criteria in the predicate tree.
-// Returns false if no valid match could be determined
+// Predicates relative to the current time are resolved when the match is made, unless the tree was
+// resolved against another time with ResolveAt. Returns false if no valid match could be determined
 func (p *Tree) FindOptimalMatch(credentials []*credential.VerifiableCredential) ([]*credential.VerifiableCredential, bool) {
+	p = p.current()
+
 	unfilteredCredentials := toVerifiableCredentialCollection(
 		credentials,
 	)
@@ -79,8 +124,11 @@ func (p *Tree) FindOptimalMatch(credentials []*credential.VerifiableCredential)
 
 // FindMissingPredicates finds missing predicates that are required but have not been met by
 // the provided credentials. The report will contain required predicates, each containing a
-// solultion of predicate options that will satisfy the requirements
+// solultion of predicate options that will satisfy the requirements. Predicates relative to the
+// current time are resolved as they are for FindOptimalMatch
 func (p *Tree) FindMissingPredicates(credentials []*credential.VerifiableCredential) *Report {
+	p = p.current()
+
 	unfilteredCredentials := toVerifiableCredentialCollection(
 		credentials,
 	)
@@ -95,6 +143,28 @@ func (p *Tree) FindMissingPredicates(credentials []*credential.VerifiableCredent
 	)
 }
 
+// Fields returns the json pointers of all fields referenced by the predicate tree
+func (p *Tree) Fields() []string {
+	var fields []string
+
+	seen := make(map[string]bool)
+
+	for _, requirement := range p.FindMissingPredicates(nil).Requirements() {
+		for _, option := range requirement.Options() {
+			for _, predicator := range option {
+				field := predicator.Field()
+
+				if !seen[field] {
+					seen[field] = true
+					fields = append(fields, field)
+				}
+			}
+		}
+	}
+
+	return fields
+}
+
 // Graphviz render the predicate tree to graphviz dot format
 func (p *Tree) Graphviz() string {
 	dotBuf := C.self_credential_predicate_tree_graphviz(
@@ -10,6 +10,8 @@ package credential
 import "C"
 import (
 	"runtime"
+	"slices"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -19,11 +21,15 @@ import (
 // TrustedIssuerRegistry a registry of trusted credential issuers
 type TrustedIssuerRegistry struct {
 	ptr *C.self_trusted_issuer_registry
+	// trusted issuers added to the registry, excluding the registry's defaults
+	trusted *TrustedIssuerList
+	mu      sync.Mutex
 }
 
 func newTrustedIssuerRegistry(ptr *C.self_trusted_issuer_registry) *TrustedIssuerRegistry {
 	r := &TrustedIssuerRegistry{
-		ptr: ptr,
+		ptr:     ptr,
+		trusted: &TrustedIssuerList{},
 	}
 
 	runtime.AddCleanup(r, func(ptr *C.self_trusted_issuer_registry) {
@@ -104,18 +110,30 @@ func DefaultIssuerEpoch() time.Time {
 
 // AddIssuer adds an issuer to the trusted issuer registry
 func (r *TrustedIssuerRegistry) AddIssuer(issuer *Address) bool {
-	return bool(C.self_trusted_issuer_registry_issuer_add(
+	added := bool(C.self_trusted_issuer_registry_issuer_add(
 		r.ptr,
 		issuer.ptr,
 	))
+
+	r.mu.Lock()
+	r.trusted.record(issuer.String())
+	r.mu.Unlock()
+
+	return added
 }
 
 // RemoveIssuer removes an issuer from the trusted issuer registry
 func (r *TrustedIssuerRegistry) RemoveIssuer(issuer *Address) bool {
-	return bool(C.self_trusted_issuer_registry_issuer_remove(
+	removed := bool(C.self_trusted_issuer_registry_issuer_remove(
 		r.ptr,
 		issuer.ptr,
 	))
+
+	r.mu.Lock()
+	r.trusted.remove(issuer.String())
+	r.mu.Unlock()
+
+	return removed
 }
 
 // GrantAuthority grants authority to an issuer for a given credential type and time period
@@ -143,6 +161,10 @@ func (r *TrustedIssuerRegistry) GrantAuthority(issuer *Address, credentialType s
 		return status.New(result)
 	}
 
+	r.mu.Lock()
+	r.trusted.grant(issuer.String(), credentialType, granted, revoked)
+	r.mu.Unlock()
+
 	return nil
 }
 
@@ -163,9 +185,37 @@ func (r *TrustedIssuerRegistry) RevokeAuthority(issuer *Address, credentialType
 		return status.New(result)
 	}
 
+	r.mu.Lock()
+	r.trusted.revoke(issuer.String(), credentialType, revoked)
+	r.mu.Unlock()
+
 	return nil
 }
 
+// Provenance returns where an issuer's authority was imported from.
+// Returns nil if the issuer was added directly to the registry
+func (r *TrustedIssuerRegistry) Provenance(issuer *Address) []*TrustedIssuerProvenance {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, trusted := range r.trusted.Issuers {
+		if trusted.Issuer == issuer.String() {
+			return slices.Clone(trusted.Provenance)
+		}
+	}
+
+	return nil
+}
+
+// Export returns a serializable list of the issuers and grants added to the registry.
+// The default issuers of the production and sandbox registries are not included
+func (r *TrustedIssuerRegistry) Export() *TrustedIssuerList {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.trusted.copy()
+}
+
 // AuthorityAt returns true if a issuer had authority to issue a given credential type at the specified time
 func (r *TrustedIssuerRegistry) AuthorityAt(issuer *Address, credentialType string, at time.Time) bool {
 	credentialTypePtr := C.CString(credentialType)
@@ -0,0 +1,243 @@
+// Package responder provides a responder that answers credential presentation requests
+// with stored credentials, subject to a consent policy
+package responder
+
+import (
+	"time"
+
+	"github.com/joinself/self-go-sdk/account"
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/credential/predicate"
+	"github.com/joinself/self-go-sdk/event"
+	"github.com/joinself/self-go-sdk/keypair/signing"
+	"github.com/joinself/self-go-sdk/message"
+)
+
+// Request describes a credential presentation request that is awaiting consent
+type Request struct {
+	// Requester the address of the account that sent the request
+	Requester *signing.PublicKey
+	// PresentationType the type of presentation requested
+	PresentationType []string
+	// CredentialType the types of credential referenced by the requests predicates
+	CredentialType []string
+	// Term the term the requester will use the credentials for, if specified
+	Term *credential.Term
+	// Holder the address the credentials will be presented by
+	Holder *credential.Address
+	// Proof the validated presentations the requester attached to support the request
+	Proof []*credential.VerifiablePresentation
+	// Report the predicates that could not be satisfied by stored credentials
+	Report *predicate.Report
+	// Credentials the stored credentials that will be shared if consent is given
+	Credentials []*credential.VerifiableCredential
+	// Satisfied true if the stored credentials satisfy all of the requests predicates
+	Satisfied bool
+	// License the license credentials are shared under. may be set by the consent policy
+	License *credential.License
+}
+
+// ConsentPolicy decides whether credentials may be shared with a requester.
+// Returning false denies the request
+type ConsentPolicy func(request *Request) bool
+
+// ConsentAlways a consent policy that shares credentials with any requester
+var ConsentAlways ConsentPolicy = func(request *Request) bool {
+	return true
+}
+
+// Responder responds to credential presentation requests
+type Responder struct {
+	account *account.Account
+	consent ConsentPolicy
+}
+
+// New creates a new responder that answers presentation requests with the accounts stored credentials
+func New(acc *account.Account, consent ConsentPolicy) *Responder {
+	return &Responder{
+		account: acc,
+		consent: consent,
+	}
+}
+
+// Respond answers a credential presentation request. The consent policy is asked before any
+// credentials are shared, and before the requester is told whether stored credentials satisfy
+// the request, along with a report of any predicates that could not be satisfied. Requests are
+// answered with:
+//   - ResponseStatusOk and a presentation of the matched credentials if consent is given
+//   - ResponseStatusForbidden if consent is denied
+//   - ResponseStatusNotFound if consent is given, but stored credentials do not satisfy the requests predicates
+//   - ResponseStatusNotAcceptable if the request has expired, is for a holder the account
+//     does not control, requires a biometric check or has proof that is not valid
+//
+// The status sent to the requester is returned
+func (r *Responder) Respond(msg *event.Message) (message.ResponseStatus, error) {
+	presentationRequest, err := message.DecodeCredentialPresentationRequest(msg.Content())
+	if err != nil {
+		return message.ResponseStatusUnknown, err
+	}
+
+	expires := presentationRequest.Expires()
+
+	// requests without an expiry have an expiry of zero
+	if expires.Unix() > 0 && time.Now().After(expires) {
+		return message.ResponseStatusNotAcceptable, r.reply(msg, message.ResponseStatusNotAcceptable, nil)
+	}
+
+	holder, err := r.holder(presentationRequest, msg.ToAddress())
+	if err != nil {
+		return message.ResponseStatusUnknown, err
+	}
+
+	// stored credentials cannot answer a request for a fresh liveness check against a biometric anchor
+	if holder == nil || !isZero(presentationRequest.BiometricAnchor()) {
+		return message.ResponseStatusNotAcceptable, r.reply(msg, message.ResponseStatusNotAcceptable, nil)
+	}
+
+	proof := presentationRequest.Proof()
+
+	for _, presentation := range proof {
+		if presentation.Validate() != nil {
+			return message.ResponseStatusNotAcceptable, r.reply(msg, message.ResponseStatusNotAcceptable, nil)
+		}
+	}
+
+	candidates, err := r.account.CredentialLookup()
+	if err != nil {
+		return message.ResponseStatusUnknown, err
+	}
+
+	predicates := presentationRequest.Predicates()
+
+	credentials, satisfied := predicates.FindOptimalMatch(candidates)
+
+	request := &Request{
+		Requester:        msg.FromAddress(),
+		PresentationType: presentationRequest.PresentationType(),
+		CredentialType:   requestedTypes(predicates),
+		Term:             presentationRequest.Term(),
+		Holder:           holder,
+		Proof:            proof,
+		Report:           predicates.FindMissingPredicates(candidates),
+		Credentials:      credentials,
+		Satisfied:        satisfied,
+	}
+
+	if r.consent == nil || !r.consent(request) {
+		return message.ResponseStatusForbidden, r.reply(msg, message.ResponseStatusForbidden, nil)
+	}
+
+	if !satisfied {
+		return message.ResponseStatusNotFound, r.reply(msg, message.ResponseStatusNotFound, nil)
+	}
+
+	presentation, err := credential.NewPresentation().
+		PresentationType(request.PresentationType...).
+		CredentialAdd(credentials...).
+		Holder(holder).
+		Finish()
+
+	if err != nil {
+		return message.ResponseStatusUnknown, err
+	}
+
+	verifiablePresentation, err := r.account.PresentationIssue(presentation)
+	if err != nil {
+		return message.ResponseStatusUnknown, err
+	}
+
+	err = r.reply(msg, message.ResponseStatusOk, verifiablePresentation)
+	if err != nil {
+		return message.ResponseStatusUnknown, err
+	}
+
+	for _, verifiableCredential := range credentials {
+		err = r.account.CredentialExchangeTrack(msg.FromAddress(), verifiableCredential, request.License)
+		if err != nil {
+			return message.ResponseStatusOk, err
+		}
+	}
+
+	return message.ResponseStatusOk, nil
+}
+
+func (r *Responder) reply(msg *event.Message, status message.ResponseStatus, presentation *credential.VerifiablePresentation) error {
+	builder := message.NewCredentialPresentationResponse().
+		ResponseTo(msg.ID()).
+		Status(status)
+
+	if presentation != nil {
+		builder.VerifiablePresentation(presentation)
+	}
+
+	content, err := builder.Finish()
+	if err != nil {
+		return err
+	}
+
+	return r.account.MessageSend(msg.FromAddress(), content)
+}
+
+// holder returns the address credentials are presented by. If the request specifies a holder,
+// it must be the address the request was sent to, or an identity the account controls.
+// Returns nil if the account cannot present credentials as the requested holder
+func (r *Responder) holder(presentationRequest *message.CredentialPresentationRequest, to *signing.PublicKey) (*credential.Address, error) {
+	holder, err := presentationRequest.Holder()
+	if err != nil || holder == nil {
+		return credential.AddressKey(to), nil
+	}
+
+	if holder.Address().Matches(to) {
+		return credential.AddressKey(to), nil
+	}
+
+	identifiers, err := r.account.IdentityList()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, identifier := range identifiers {
+		if holder.Address().Matches(identifier) {
+			return credential.AddressAureWithKey(identifier, to), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// isZero returns true if a value is empty or only contains zeros
+func isZero(value []byte) bool {
+	for _, b := range value {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// requestedTypes returns the credential types referenced by the predicates
+func requestedTypes(predicates *predicate.Tree) []string {
+	var credentialType []string
+
+	seen := make(map[string]bool)
+
+	for _, requirement := range predicates.FindMissingPredicates(nil).Requirements() {
+		for _, option := range requirement.Options() {
+			for _, predicator := range option {
+				if predicator.Field() != credential.FieldType {
+					continue
+				}
+
+				for _, value := range predicator.Values() {
+					if !seen[value] {
+						seen[value] = true
+						credentialType = append(credentialType, value)
+					}
+				}
+			}
+		}
+	}
+
+	return credentialType
+}
@@ -0,0 +1,47 @@
+package credential
+
+import (
+	"time"
+
+	"github.com/joinself/self-go-sdk/revocation"
+)
+
+// RevokedAt checks a cache of revocation statements for the credential's revocation, without requiring
+// access to the network. Returns false if the credential has not been revoked by it's signer
+func (c *VerifiableCredential) RevokedAt(cache *revocation.Cache) (time.Time, bool, error) {
+	signer, err := c.Signer()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	revocationHash, err := c.RevocationHash()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	signingKey := signer.SigningKey()
+	if signingKey == nil {
+		signingKey = signer.Address()
+	}
+
+	return cache.RevokedAt(signingKey, revocationHash)
+}
+
+// RevokedCredentials returns the credentials in the presentation that have been revoked
+// at the given time, according to a cache of revocation statements
+func (p *VerifiablePresentation) RevokedCredentials(cache *revocation.Cache, at time.Time) ([]*VerifiableCredential, error) {
+	var revoked []*VerifiableCredential
+
+	for _, c := range p.Credentials() {
+		revokedAt, ok, err := c.RevokedAt(cache)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok && !revokedAt.After(at) {
+			revoked = append(revoked, c)
+		}
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,340 @@
+package credential
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSchemaViolation returned when a credentials claims do not conform to the schema for it's type
+var ErrSchemaViolation = errors.New("credential claims violate schema")
+
+var (
+	schemas = map[string]*Schema{
+		CredentialTypeEmail: {
+			Type:     "object",
+			Required: []string{"email"},
+			Properties: map[string]*Schema{
+				"email": strictObject(map[string]*Schema{
+					"emailAddress": {Type: "string", Format: "email"},
+				}, "emailAddress"),
+			},
+		},
+		CredentialTypePhone: {
+			Type:     "object",
+			Required: []string{"phone"},
+			Properties: map[string]*Schema{
+				"phone": strictObject(map[string]*Schema{
+					"phoneNumber": {Type: "string", Pattern: `^\+[1-9][0-9]{1,14}$`},
+				}, "phoneNumber"),
+			},
+		},
+		CredentialTypePassport: {
+			Type: "object",
+			Properties: map[string]*Schema{
+				"passport": strictObject(map[string]*Schema{
+					"documentNumber":    {Type: "string"},
+					"givenNames":        {Type: "string"},
+					"surname":           {Type: "string"},
+					"sex":               {Type: "string"},
+					"nationality":       {Type: "string"},
+					"dateOfBirth":       {Type: "string"},
+					"dateOfExpiration":  {Type: "string"},
+					"countryOfIssuance": {Type: "string"},
+					"mrz":               {Type: "string"},
+					"imageType":         {Type: "string"},
+					"imageHash":         {Type: "string"},
+					"targetImageHash":   {Type: "string"},
+				}),
+			},
+		},
+		CredentialTypeOrganisation: {
+			Type:     "object",
+			Required: []string{"organisation"},
+			Properties: map[string]*Schema{
+				"organisation": strictObject(map[string]*Schema{
+					"organisationName": {Type: "string", MinLength: intPtr(1)},
+				}, "organisationName"),
+			},
+		},
+		CredentialTypeApplication: {
+			Type:     "object",
+			Required: []string{"application"},
+			Properties: map[string]*Schema{
+				"application": strictObject(map[string]*Schema{
+					"applicationName": {Type: "string", MinLength: intPtr(1)},
+					"subsidiaryOf":    {Type: "string"},
+				}, "applicationName"),
+			},
+		},
+	}
+	schemasMu sync.RWMutex
+)
+
+// Schema a json schema describing the claims of a credential subject.
+// Supports a subset of json schema, covering the type, properties, required,
+// additionalProperties, items, enum, pattern, format, minLength, maxLength,
+// minimum and maximum keywords. Supported formats are email, date and date-time
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+// DecodeSchema decodes a json schema
+func DecodeSchema(encodedSchema []byte) (*Schema, error) {
+	var schema Schema
+	return &schema, json.Unmarshal(encodedSchema, &schema)
+}
+
+// SchemaRegister registers a schema for the claims of a credential type, replacing any existing schema
+func SchemaRegister(credentialType string, schema *Schema) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+
+	schemas[credentialType] = schema
+}
+
+// SchemaLookup returns the schema registered for a credential type
+func SchemaLookup(credentialType string) (*Schema, bool) {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+
+	schema, ok := schemas[credentialType]
+
+	return schema, ok
+}
+
+// ValidateClaims validates claims against the schemas registered for each of the credential types.
+// Credential types without a registered schema are not validated
+func ValidateClaims(credentialType []string, claims map[string]interface{}) error {
+	// normalize the claims to the types they will be decoded as
+	encodedClaims, err := json.Marshal(claims)
+	if err != nil {
+		return err
+	}
+
+	var normalized interface{}
+
+	err = json.Unmarshal(encodedClaims, &normalized)
+	if err != nil {
+		return err
+	}
+
+	if normalized == nil {
+		normalized = map[string]interface{}{}
+	}
+
+	for _, typ := range credentialType {
+		schema, ok := SchemaLookup(typ)
+		if !ok {
+			continue
+		}
+
+		err = schema.Validate(normalized)
+		if err != nil {
+			return fmt.Errorf("%s: %w", typ, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateClaims validates the credentials claims against the schemas registered for it's type
+func (c *VerifiableCredential) ValidateClaims() error {
+	claims, err := c.CredentialSubjectClaims()
+	if err != nil {
+		return err
+	}
+
+	// the subject's id is not a claim
+	delete(claims, "id")
+
+	return ValidateClaims(c.CredentialType(), claims)
+}
+
+// Validate validates a decoded json value against the schema. Returns an
+// error describing each violation and the json pointer of the invalid value
+func (s *Schema) Validate(value interface{}) error {
+	var violations []string
+
+	s.validate(value, "", &violations)
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%w: %s", ErrSchemaViolation, strings.Join(violations, ", "))
+	}
+
+	return nil
+}
+
+func (s *Schema) validate(value interface{}, pointer string, violations *[]string) {
+	violation := func(format string, args ...interface{}) {
+		location := pointer
+		if location == "" {
+			location = "/"
+		}
+
+		*violations = append(*violations, location+" "+fmt.Sprintf(format, args...))
+	}
+
+	if s.Type != "" && !schemaTypeMatches(s.Type, value) {
+		violation("must be of type %s", s.Type)
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		var found bool
+
+		for _, e := range s.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			violation("must be one of %v", s.Enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, required := range s.Required {
+			if _, ok := v[required]; !ok {
+				violation("missing required property %s", required)
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			property, ok := s.Properties[key]
+			if ok {
+				property.validate(v[key], pointer+"/"+escapePointer(key), violations)
+				continue
+			}
+
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				violation("unexpected property %s", key)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, fmt.Sprintf("%s/%d", pointer, i), violations)
+			}
+		}
+	case string:
+		length := len([]rune(v))
+
+		if s.MinLength != nil && length < *s.MinLength {
+			violation("must be at least %d characters", *s.MinLength)
+		}
+
+		if s.MaxLength != nil && length > *s.MaxLength {
+			violation("must be at most %d characters", *s.MaxLength)
+		}
+
+		if s.Pattern != "" {
+			matched, err := regexp.MatchString(s.Pattern, v)
+			if err != nil || !matched {
+				violation("must match pattern %s", s.Pattern)
+			}
+		}
+
+		if s.Format != "" && !schemaFormatMatches(s.Format, v) {
+			violation("must be a valid %s", s.Format)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			violation("must be at least %v", *s.Minimum)
+		}
+
+		if s.Maximum != nil && v > *s.Maximum {
+			violation("must be at most %v", *s.Maximum)
+		}
+	}
+}
+
+func schemaTypeMatches(typ string, value interface{}) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		v, ok := value.(float64)
+		return ok && v == math.Trunc(v)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return true
+	}
+}
+
+func schemaFormatMatches(format, value string) bool {
+	switch format {
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "date":
+		_, err := time.Parse(time.DateOnly, value)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	default:
+		return true
+	}
+}
+
+func strictObject(properties map[string]*Schema, required ...string) *Schema {
+	additionalProperties := false
+
+	return &Schema{
+		Type:                 "object",
+		Properties:           properties,
+		Required:             required,
+		AdditionalProperties: &additionalProperties,
+	}
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,211 @@
+package sdjwt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Disclosure a selectively disclosable claim
+type Disclosure struct {
+	// Path the json pointer of the claim
+	Path    string
+	Salt    string
+	Name    string
+	Value   interface{}
+	encoded string
+}
+
+// Encoded returns the base64url encoded disclosure
+func (d *Disclosure) Encoded() string {
+	return d.encoded
+}
+
+// Digest returns the base64url encoded sha-256 digest of the disclosure
+func (d *Disclosure) Digest() string {
+	return digest(d.encoded)
+}
+
+func newDisclosure(path, name string, value interface{}) (*Disclosure, error) {
+	salt := make([]byte, 16)
+
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Disclosure{
+		Path:  path,
+		Salt:  base64.RawURLEncoding.EncodeToString(salt),
+		Name:  name,
+		Value: value,
+	}
+
+	encodedDisclosure, err := json.Marshal([]interface{}{d.Salt, d.Name, d.Value})
+	if err != nil {
+		return nil, err
+	}
+
+	d.encoded = base64.RawURLEncoding.EncodeToString(encodedDisclosure)
+
+	return d, nil
+}
+
+func decodeDisclosure(encoded string) (*Disclosure, error) {
+	encodedDisclosure, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var elements []interface{}
+
+	err = json.Unmarshal(encodedDisclosure, &elements)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(elements) != 3 {
+		return nil, errors.New("disclosure must contain a salt, claim name and claim value")
+	}
+
+	salt, ok := elements[0].(string)
+	if !ok {
+		return nil, errors.New("disclosure salt must be a string")
+	}
+
+	name, ok := elements[1].(string)
+	if !ok || name == sdKey || name == "..." {
+		return nil, errors.New("disclosure claim name is invalid")
+	}
+
+	return &Disclosure{
+		Salt:    salt,
+		Name:    name,
+		Value:   elements[2],
+		encoded: encoded,
+	}, nil
+}
+
+// conceal replaces the disclosable claims of an object with their digests,
+// returning the disclosures for each concealed claim
+func conceal(claims map[string]interface{}, path string, disclosable map[string]bool) (map[string]interface{}, []*Disclosure, error) {
+	concealed := make(map[string]interface{})
+
+	var disclosures []*Disclosure
+	var digests []string
+
+	keys := make([]string, 0, len(claims))
+	for key := range claims {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := claims[key]
+		pointer := path + "/" + escapePointer(key)
+
+		if object, ok := value.(map[string]interface{}); ok {
+			concealedObject, nested, err := conceal(object, pointer, disclosable)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			value = concealedObject
+			disclosures = append(disclosures, nested...)
+		}
+
+		if !disclosable[pointer] {
+			concealed[key] = value
+			continue
+		}
+
+		disclosure, err := newDisclosure(pointer, key, value)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		disclosures = append(disclosures, disclosure)
+		digests = append(digests, disclosure.Digest())
+	}
+
+	if len(digests) > 0 {
+		// digests are sorted so their order does not reveal the order of the claims
+		sort.Strings(digests)
+		concealed[sdKey] = digests
+	}
+
+	return concealed, disclosures, nil
+}
+
+// reveal replaces the digests of an object with the claims of the matching disclosures,
+// recording the path of each disclosure. Each disclosure may only be referenced once
+func reveal(claims map[string]interface{}, path string, disclosures map[string]*Disclosure, referenced map[string]bool) (map[string]interface{}, error) {
+	revealed := make(map[string]interface{})
+
+	for key, value := range claims {
+		if key == sdKey {
+			continue
+		}
+
+		revealed[key] = value
+	}
+
+	if digests, ok := claims[sdKey].([]interface{}); ok {
+		for _, d := range digests {
+			digest, ok := d.(string)
+			if !ok {
+				return nil, errors.New("invalid disclosure digest")
+			}
+
+			disclosure, ok := disclosures[digest]
+			if !ok {
+				// the claim has not been disclosed
+				continue
+			}
+
+			if referenced[digest] {
+				return nil, errors.New("disclosure referenced more than once")
+			}
+
+			referenced[digest] = true
+
+			if _, exists := revealed[disclosure.Name]; exists {
+				return nil, fmt.Errorf("disclosed claim %s already exists", disclosure.Name)
+			}
+
+			disclosure.Path = path + "/" + escapePointer(disclosure.Name)
+			revealed[disclosure.Name] = disclosure.Value
+		}
+	}
+
+	for key, value := range revealed {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		revealedObject, err := reveal(object, path+"/"+escapePointer(key), disclosures, referenced)
+		if err != nil {
+			return nil, err
+		}
+
+		revealed[key] = revealedObject
+	}
+
+	return revealed, nil
+}
+
+func digest(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
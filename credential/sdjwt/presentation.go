@@ -0,0 +1,299 @@
+package sdjwt
+
+import (
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/credential/predicate"
+	"github.com/joinself/self-go-sdk/message"
+)
+
+// MaxKeyBindingAge the maximum age of a key binding jwt accepted by verifiers
+var MaxKeyBindingAge = 5 * time.Minute
+
+// Present creates a presentation of the credential that discloses only the given claims, along with any
+// claims that are always disclosed. Claims are json pointers relative to the credential subject, such as
+// "/passport/nationality", or credential fields, such as credential.FieldSubjectPassportNationality.
+// If the credential is bound to a holder, the presentation is signed by the holder's key,
+// which must be held by the signer
+func (c *Credential) Present(signer Signer, audience string, nonce []byte, claims ...string) (*Credential, error) {
+	var disclosures []*Disclosure
+
+	for _, disclosure := range c.disclosures {
+		for _, claim := range claims {
+			pointer := subjectPointer(claim)
+
+			// a disclosure is required if it contains the claim, or is contained by the claim
+			if pointerContains(disclosure.Path, pointer) || pointerContains(pointer, disclosure.Path) {
+				disclosures = append(disclosures, disclosure)
+				break
+			}
+		}
+	}
+
+	presentation := &Credential{
+		jwt:         c.jwt,
+		header:      c.header,
+		payload:     c.payload,
+		disclosures: disclosures,
+	}
+
+	holder := c.holder()
+
+	if holder != nil {
+		if holder.SigningKey() == nil {
+			return nil, fmt.Errorf("%w: holder address does not specify a signing key", ErrKeyBinding)
+		}
+
+		keyBinding, err := signJWT(
+			signer,
+			holder.SigningKey(),
+			map[string]interface{}{
+				"alg": algorithm,
+				"typ": TypeKeyBinding,
+			},
+			map[string]interface{}{
+				"iat":     time.Now().Unix(),
+				"aud":     audience,
+				"nonce":   base64.RawURLEncoding.EncodeToString(nonce),
+				"sd_hash": digest(presentation.Encode()),
+			},
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		presentation.keyBinding = keyBinding
+	}
+
+	return Decode(presentation.Encode())
+}
+
+// PresentFor creates a presentation of the credential that discloses only the claims required by the predicates
+// of a presentation request. Where predicates can be satisfied in more than one way, such as predicates joined
+// with or, only the claims of the first option the credential satisfies are disclosed. The presentation is bound
+// to the request's challenge and the audience
+func (c *Credential) PresentFor(signer Signer, request *message.CredentialPresentationRequest, audience string) (*Credential, error) {
+	var claims []string
+
+	for _, requirement := range request.Predicates().FindMissingPredicates(nil).Requirements() {
+		for _, option := range requirement.Options() {
+			if !c.satisfies(option) {
+				continue
+			}
+
+			for _, predicator := range option {
+				claims = append(claims, predicator.Field())
+			}
+
+			break
+		}
+	}
+
+	return c.Present(signer, audience, request.Challenge(), claims...)
+}
+
+// VerifyPresentation verifies the credential and, if the credential is bound to a holder, that the
+// presentation was signed by the holder for the audience and nonce
+func (c *Credential) VerifyPresentation(resolve Resolver, audience string, nonce []byte) error {
+	err := c.Verify(resolve)
+	if err != nil {
+		return err
+	}
+
+	holder := c.holder()
+
+	if holder == nil {
+		return nil
+	}
+
+	if c.keyBinding == "" || holder.SigningKey() == nil {
+		return fmt.Errorf("%w: key binding missing", ErrKeyBinding)
+	}
+
+	header, payload, err := decodeJWT(c.keyBinding)
+	if err != nil {
+		return err
+	}
+
+	if header["typ"] != TypeKeyBinding {
+		return fmt.Errorf("%w: invalid key binding type", ErrKeyBinding)
+	}
+
+	err = verifyJWT(c.keyBinding, holder.SigningKey())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrKeyBinding, err)
+	}
+
+	if payload["aud"] != audience || payload["nonce"] != base64.RawURLEncoding.EncodeToString(nonce) {
+		return fmt.Errorf("%w: audience or nonce does not match", ErrKeyBinding)
+	}
+
+	issuedAt := numericDate(payload["iat"])
+
+	if issuedAt.IsZero() || time.Since(issuedAt) > MaxKeyBindingAge || time.Until(issuedAt) > time.Minute {
+		return fmt.Errorf("%w: key binding expired", ErrKeyBinding)
+	}
+
+	unbound := encode(c.jwt, c.disclosures, "")
+
+	if payload["sd_hash"] != digest(unbound) {
+		return fmt.Errorf("%w: sd_hash does not match", ErrKeyBinding)
+	}
+
+	return nil
+}
+
+// holder returns the address of the holder the credential is bound to
+func (c *Credential) holder() *credential.Address {
+	cnf, ok := c.payload["cnf"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	kid, _ := cnf["kid"].(string)
+
+	holder, err := credential.DecodeAddress(kid)
+	if err != nil {
+		return nil
+	}
+
+	return holder
+}
+
+// satisfies returns true if the credential's claims satisfy all of an option's predicators. Predicators
+// on fields outside of the credential subject cannot be disclosed, so are not evaluated
+func (c *Credential) satisfies(option []*predicate.Predicator) bool {
+	for _, predicator := range option {
+		pointer := subjectPointer(predicator.Field())
+		if pointer == predicator.Field() {
+			continue
+		}
+
+		value, found := lookupPointer(c.claims, pointer)
+
+		if !predicatorMatches(predicator.Type(), value, found, predicator.Values()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// predicatorMatches evaluates a predicator against a claim. Claims are compared as numbers if both the
+// claim and value are numeric, and otherwise as strings, which orders dates and date times correctly
+func predicatorMatches(predicatorType predicate.PredicatorType, value interface{}, found bool, values []string) bool {
+	switch predicatorType {
+	case predicate.PredicatorTypeEmpty:
+		return !found || emptyClaim(value)
+	case predicate.PredicatorTypeNotEmpty:
+		return found && !emptyClaim(value)
+	}
+
+	if !found || len(values) < 1 {
+		// missing claims only satisfy negative predicators
+		return !found && (predicatorType == predicate.PredicatorTypeNotEquals ||
+			predicatorType == predicate.PredicatorTypeNotContains ||
+			predicatorType == predicate.PredicatorTypeNotOneOf)
+	}
+
+	equals := func(target string) bool {
+		return compareClaim(value, target) == 0
+	}
+
+	switch predicatorType {
+	case predicate.PredicatorTypeEquals:
+		return equals(values[0])
+	case predicate.PredicatorTypeNotEquals:
+		return !equals(values[0])
+	case predicate.PredicatorTypeGreaterThan:
+		return compareClaim(value, values[0]) > 0
+	case predicate.PredicatorTypeGreaterThanOrEquals:
+		return compareClaim(value, values[0]) >= 0
+	case predicate.PredicatorTypeLessThan:
+		return compareClaim(value, values[0]) < 0
+	case predicate.PredicatorTypeLessThanOrEquals:
+		return compareClaim(value, values[0]) <= 0
+	case predicate.PredicatorTypeContains:
+		return containsClaim(value, values[0])
+	case predicate.PredicatorTypeNotContains:
+		return !containsClaim(value, values[0])
+	case predicate.PredicatorTypeOneOf:
+		return slices.ContainsFunc(values, equals)
+	case predicate.PredicatorTypeNotOneOf:
+		return !slices.ContainsFunc(values, equals)
+	default:
+		return false
+	}
+}
+
+func compareClaim(value interface{}, target string) int {
+	if n, ok := value.(float64); ok {
+		t, err := strconv.ParseFloat(target, 64)
+		if err == nil {
+			return cmp.Compare(n, t)
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(value), target)
+}
+
+func containsClaim(value interface{}, target string) bool {
+	switch v := value.(type) {
+	case string:
+		return strings.Contains(v, target)
+	case []interface{}:
+		return slices.ContainsFunc(v, func(element interface{}) bool {
+			return compareClaim(element, target) == 0
+		})
+	default:
+		return false
+	}
+}
+
+func emptyClaim(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// lookupPointer returns the claim at a json pointer relative to the credential subject
+func lookupPointer(claims map[string]interface{}, pointer string) (interface{}, bool) {
+	var value interface{} = claims
+
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		value, ok = object[token]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+func pointerContains(parent, child string) bool {
+	return parent == child || strings.HasPrefix(child, parent+"/")
+}
@@ -0,0 +1,493 @@
+// Package sdjwt issues, presents and verifies selective disclosure JWT verifiable credentials (SD-JWT VC),
+// signed with keys held by an account
+package sdjwt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/identity"
+	"github.com/joinself/self-go-sdk/keypair/signing"
+)
+
+const (
+	// TypeCredential the media type of issuer signed sd-jwt vcs
+	TypeCredential = "dc+sd-jwt"
+	// TypeKeyBinding the media type of key binding jwts
+	TypeKeyBinding = "kb+jwt"
+
+	// MaxClockSkew the maximum period an sd-jwt's issued at time can be ahead of the verifier's clock
+	MaxClockSkew = time.Minute * 5
+
+	algorithm = "EdDSA"
+	sdKey     = "_sd"
+	sdAlgKey  = "_sd_alg"
+	sdAlg     = "sha-256"
+)
+
+var (
+	// ErrInvalidSignature returned when the signature of an sd-jwt or key binding jwt is invalid
+	ErrInvalidSignature = errors.New("sd-jwt signature invalid")
+	// ErrUnauthorizedSigner returned when an sd-jwt was signed by a key that is not authorized by the issuer
+	ErrUnauthorizedSigner = errors.New("sd-jwt signer not authorized")
+	// ErrExpired returned when an sd-jwt is not within it's validity period
+	ErrExpired = errors.New("sd-jwt expired or not yet valid")
+	// ErrKeyBinding returned when a presentation's key binding is missing or does not match
+	ErrKeyBinding = errors.New("sd-jwt key binding invalid")
+)
+
+// Signer signs payloads with a key held by an account
+type Signer interface {
+	KeychainSign(address *signing.PublicKey, payload []byte) ([]byte, error)
+}
+
+// Resolver resolves the identity document of an aure address, such as Account.IdentityResolve
+type Resolver func(address *signing.PublicKey) (*identity.Document, error)
+
+// Credential an sd-jwt verifiable credential, with the disclosures for the claims being disclosed
+type Credential struct {
+	jwt         string
+	header      map[string]interface{}
+	payload     map[string]interface{}
+	claims      map[string]interface{}
+	disclosures []*Disclosure
+	keyBinding  string
+}
+
+// CredentialBuilder builds sd-jwt verifiable credentials
+type CredentialBuilder struct {
+	credentialType string
+	issuer         *credential.Address
+	subject        *credential.Address
+	holder         *credential.Address
+	claims         map[string]interface{}
+	disclosable    map[string]bool
+	validFrom      time.Time
+	validUntil     time.Time
+}
+
+// NewCredential creates a new sd-jwt credential builder
+func NewCredential() *CredentialBuilder {
+	return &CredentialBuilder{
+		claims:      make(map[string]interface{}),
+		disclosable: make(map[string]bool),
+	}
+}
+
+// CredentialType sets the type of the credential
+func (b *CredentialBuilder) CredentialType(credentialType string) *CredentialBuilder {
+	b.credentialType = credentialType
+	return b
+}
+
+// Issuer sets the address of the credential's issuer. The address must specify the signing key
+// used to sign the credential, such as one created with credential.AddressAureWithKey
+func (b *CredentialBuilder) Issuer(issuerAddress *credential.Address) *CredentialBuilder {
+	b.issuer = issuerAddress
+	return b
+}
+
+// CredentialSubject sets the address of the credential's subject
+func (b *CredentialBuilder) CredentialSubject(subjectAddress *credential.Address) *CredentialBuilder {
+	b.subject = subjectAddress
+	return b
+}
+
+// Holder binds the credential to a holder's key, which must sign any presentations of the
+// credential. The address must specify the holder's signing key
+func (b *CredentialBuilder) Holder(holderAddress *credential.Address) *CredentialBuilder {
+	b.holder = holderAddress
+	return b
+}
+
+// CredentialSubjectClaims adds a collection of claims about the subject to the credential
+func (b *CredentialBuilder) CredentialSubjectClaims(claims map[string]interface{}) *CredentialBuilder {
+	for key, value := range claims {
+		b.claims[key] = value
+	}
+
+	return b
+}
+
+// Disclosable marks claims as selectively disclosable, so they are only revealed to
+// verifiers when disclosed by the holder. Claims are json pointers relative to the
+// credential subject, such as "/passport/nationality"
+func (b *CredentialBuilder) Disclosable(claims ...string) *CredentialBuilder {
+	for _, claim := range claims {
+		b.disclosable[subjectPointer(claim)] = true
+	}
+
+	return b
+}
+
+// ValidFrom sets the point of validity for the credential
+func (b *CredentialBuilder) ValidFrom(timestamp time.Time) *CredentialBuilder {
+	b.validFrom = timestamp
+	return b
+}
+
+// ValidUntil sets the end of the validity period for the credential
+func (b *CredentialBuilder) ValidUntil(timestamp time.Time) *CredentialBuilder {
+	b.validUntil = timestamp
+	return b
+}
+
+// Issue signs the credential with the issuer's signing key, which must be held by the signer
+func (b *CredentialBuilder) Issue(signer Signer) (*Credential, error) {
+	if b.credentialType == "" || b.issuer == nil {
+		return nil, errors.New("sd-jwt credential requires a credential type and issuer")
+	}
+
+	if b.issuer.SigningKey() == nil {
+		return nil, errors.New("sd-jwt issuer address must specify a signing key")
+	}
+
+	err := credential.ValidateClaims([]string{b.credentialType}, b.claims)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, disclosures, err := conceal(b.claims, "", b.disclosable)
+	if err != nil {
+		return nil, err
+	}
+
+	issuedAt := time.Now()
+
+	payload["iss"] = issuerDID(b.issuer)
+	payload["iat"] = issuedAt.Unix()
+	payload["vct"] = b.credentialType
+	payload[sdAlgKey] = sdAlg
+
+	if b.subject != nil {
+		payload["sub"] = b.subject.String()
+	}
+
+	if !b.validFrom.IsZero() {
+		payload["nbf"] = b.validFrom.Unix()
+	}
+
+	if !b.validUntil.IsZero() {
+		payload["exp"] = b.validUntil.Unix()
+	}
+
+	if b.holder != nil {
+		payload["cnf"] = map[string]interface{}{
+			"kid": b.holder.String(),
+		}
+	}
+
+	header := map[string]interface{}{
+		"alg": algorithm,
+		"typ": TypeCredential,
+		"kid": b.issuer.String(),
+	}
+
+	jwt, err := signJWT(signer, b.issuer.SigningKey(), header, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(encode(jwt, disclosures, ""))
+}
+
+// Decode decodes an sd-jwt in it's compact serialization, revealing the disclosed claims.
+// The signatures of the sd-jwt are not verified until Verify is called
+func Decode(encodedCredential string) (*Credential, error) {
+	parts := strings.Split(encodedCredential, "~")
+	if len(parts) < 2 {
+		return nil, errors.New("invalid sd-jwt serialization")
+	}
+
+	c := &Credential{
+		jwt:        parts[0],
+		keyBinding: parts[len(parts)-1],
+	}
+
+	var err error
+
+	c.header, c.payload, err = decodeJWT(c.jwt)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.payload[sdAlgKey] != nil && c.payload[sdAlgKey] != sdAlg {
+		return nil, fmt.Errorf("unsupported sd-jwt digest algorithm %v", c.payload[sdAlgKey])
+	}
+
+	disclosures := make(map[string]*Disclosure)
+
+	for _, encodedDisclosure := range parts[1 : len(parts)-1] {
+		disclosure, err := decodeDisclosure(encodedDisclosure)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, exists := disclosures[disclosure.Digest()]; exists {
+			return nil, errors.New("duplicate sd-jwt disclosure")
+		}
+
+		disclosures[disclosure.Digest()] = disclosure
+		c.disclosures = append(c.disclosures, disclosure)
+	}
+
+	referenced := make(map[string]bool)
+
+	c.claims, err = reveal(c.payload, "", disclosures, referenced)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(referenced) != len(disclosures) {
+		return nil, errors.New("sd-jwt contains disclosures that are not referenced")
+	}
+
+	for _, registered := range []string{"iss", "iat", "nbf", "exp", "vct", "sub", "cnf", sdAlgKey, "status"} {
+		delete(c.claims, registered)
+	}
+
+	return c, nil
+}
+
+// Encode encodes the credential in it's compact serialization
+func (c *Credential) Encode() string {
+	return encode(c.jwt, c.disclosures, c.keyBinding)
+}
+
+// CredentialType returns the type of the credential
+func (c *Credential) CredentialType() string {
+	vct, _ := c.payload["vct"].(string)
+	return vct
+}
+
+// Issuer returns the address of the credential's issuer
+func (c *Credential) Issuer() (*credential.Address, error) {
+	iss, _ := c.payload["iss"].(string)
+	return credential.DecodeAddress(iss)
+}
+
+// CredentialSubject returns the address of the credential's subject, or nil if not specified
+func (c *Credential) CredentialSubject() *credential.Address {
+	sub, _ := c.payload["sub"].(string)
+	if sub == "" {
+		return nil
+	}
+
+	subject, err := credential.DecodeAddress(sub)
+	if err != nil {
+		return nil
+	}
+
+	return subject
+}
+
+// CredentialSubjectClaims returns the claims that are always disclosed, and the selectively disclosable claims that have been disclosed
+func (c *Credential) CredentialSubjectClaims() map[string]interface{} {
+	return c.claims
+}
+
+// Disclosures returns the disclosures of the selectively disclosable claims that have been disclosed
+func (c *Credential) Disclosures() []*Disclosure {
+	return c.disclosures
+}
+
+// ValidFrom returns the time the credential is valid from, or the zero time if not specified
+func (c *Credential) ValidFrom() time.Time {
+	return numericDate(c.payload["nbf"])
+}
+
+// ValidUntil returns the time the credential is valid until, or the zero time if the credential does not expire
+func (c *Credential) ValidUntil() time.Time {
+	return numericDate(c.payload["exp"])
+}
+
+// Verify verifies the issuer's signature, and that the credential is within it's validity period.
+// The credential must be signed by a key that currently has the assertion role for the issuer's
+// identity, or by the issuer's own key for did:key issuers. A resolver is required for credentials
+// issued by aure addresses
+func (c *Credential) Verify(resolve Resolver) error {
+	kid, _ := c.header["kid"].(string)
+
+	signingAddress, err := credential.DecodeAddress(kid)
+	if err != nil || signingAddress.SigningKey() == nil {
+		return fmt.Errorf("%w: invalid key id", ErrInvalidSignature)
+	}
+
+	issuer, err := c.Issuer()
+	if err != nil {
+		return err
+	}
+
+	if !signingAddress.Address().Matches(issuer.Address()) {
+		return fmt.Errorf("%w: signing key does not belong to issuer", ErrUnauthorizedSigner)
+	}
+
+	switch issuer.Method() {
+	case credential.MethodKey:
+		// a did:key issuer has no other keys, so the credential must be signed by the issuer itself
+		if !signingAddress.SigningKey().Matches(issuer.Address()) {
+			return fmt.Errorf("%w: signing key does not belong to issuer", ErrUnauthorizedSigner)
+		}
+	case credential.MethodAure:
+	default:
+		return fmt.Errorf("%w: unsupported issuer method", ErrUnauthorizedSigner)
+	}
+
+	err = verifyJWT(c.jwt, signingAddress.SigningKey())
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	if numericDate(c.payload["iat"]).After(now.Add(MaxClockSkew)) {
+		return fmt.Errorf("%w: issued in the future", ErrExpired)
+	}
+
+	if !c.ValidFrom().IsZero() && now.Before(c.ValidFrom()) {
+		return ErrExpired
+	}
+
+	if !c.ValidUntil().IsZero() && now.After(c.ValidUntil()) {
+		return ErrExpired
+	}
+
+	if issuer.Method() != credential.MethodAure {
+		return nil
+	}
+
+	if resolve == nil {
+		return fmt.Errorf("%w: a resolver is required to verify aure addresses", ErrUnauthorizedSigner)
+	}
+
+	document, err := resolve(issuer.Address())
+	if err != nil {
+		return err
+	}
+
+	// iat is controlled by the signer, so roles are checked at the time of
+	// verification rather than when the signer claims the credential was issued
+	if !document.HasRolesAt(signingAddress.SigningKey(), identity.RoleAssertion, now) {
+		return ErrUnauthorizedSigner
+	}
+
+	return nil
+}
+
+func encode(jwt string, disclosures []*Disclosure, keyBinding string) string {
+	var encoded strings.Builder
+
+	encoded.WriteString(jwt)
+	encoded.WriteByte('~')
+
+	for _, disclosure := range disclosures {
+		encoded.WriteString(disclosure.encoded)
+		encoded.WriteByte('~')
+	}
+
+	encoded.WriteString(keyBinding)
+
+	return encoded.String()
+}
+
+func signJWT(signer Signer, signingKey *signing.PublicKey, header, payload map[string]interface{}) (string, error) {
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	encodedPayload, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(encodedHeader) + "." + base64.RawURLEncoding.EncodeToString(encodedPayload)
+
+	signature, err := signer.KeychainSign(signingKey, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func decodeJWT(jwt string) (map[string]interface{}, map[string]interface{}, error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return nil, nil, errors.New("invalid jwt")
+	}
+
+	var header, payload map[string]interface{}
+
+	for i, target := range []*map[string]interface{}{&header, &payload} {
+		decoded, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = json.Unmarshal(decoded, target)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if header["alg"] != algorithm {
+		return nil, nil, fmt.Errorf("unsupported jwt algorithm %v", header["alg"])
+	}
+
+	return header, payload, nil
+}
+
+func verifyJWT(jwt string, signingKey *signing.PublicKey) error {
+	separator := strings.LastIndexByte(jwt, '.')
+	if separator < 0 {
+		return ErrInvalidSignature
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(jwt[separator+1:])
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	// signing keys are encoded with a leading key type
+	publicKey := ed25519.PublicKey(signingKey.Bytes()[1:])
+
+	if !ed25519.Verify(publicKey, []byte(jwt[:separator]), signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func issuerDID(issuer *credential.Address) string {
+	if issuer.Method() == credential.MethodAure {
+		return credential.AddressAure(issuer.Address()).String()
+	}
+
+	return credential.AddressKey(issuer.Address()).String()
+}
+
+func numericDate(value interface{}) time.Time {
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(seconds), 0)
+}
+
+// subjectPointer converts credential fields, such as "/credentialSubject/passport/nationality",
+// to pointers relative to the credential subject
+func subjectPointer(field string) string {
+	if strings.HasPrefix(field, credential.FieldSubjectClaims+"/") {
+		return strings.TrimPrefix(field, credential.FieldSubjectClaims)
+	}
+
+	return field
+}
@@ -0,0 +1,389 @@
+package credential
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/joinself/self-go-sdk/keypair/signing"
+)
+
+const (
+	TrustedIssuerChangeAdded TrustedIssuerChangeKind = iota
+	TrustedIssuerChangeRemoved
+	TrustedIssuerChangeModified
+)
+
+var (
+	// ErrTrustedIssuerSignature returned when a trusted issuer list's signature is invalid, or is not from a governance key
+	ErrTrustedIssuerSignature = errors.New("trusted issuer list signature invalid")
+)
+
+// Signer signs payloads with a key held by an account
+type Signer interface {
+	KeychainSign(address *signing.PublicKey, payload []byte) ([]byte, error)
+}
+
+// TrustedIssuerGrant a period an issuer is authorized to issue a type of credential
+type TrustedIssuerGrant struct {
+	CredentialType string     `json:"credentialType"`
+	Granted        time.Time  `json:"granted"`
+	Revoked        *time.Time `json:"revoked,omitempty"`
+}
+
+// TrustedIssuerProvenance records where an issuer's authority was imported from
+type TrustedIssuerProvenance struct {
+	// Format the format of the trust list, such as etsi-ts-119-612 or ebsi
+	Format string `json:"format"`
+	// Source the location the trust list was loaded from
+	Source string `json:"source"`
+	// Operator the operator of the trust list, or the authority that accredited the issuer
+	Operator string `json:"operator,omitempty"`
+	// Reference identifies the issuer's entry within the trust list
+	Reference string    `json:"reference,omitempty"`
+	Imported  time.Time `json:"imported"`
+}
+
+// TrustedIssuer an issuer and the credentials it is authorized to issue
+type TrustedIssuer struct {
+	// Issuer the did of the issuer's address
+	Issuer     string                     `json:"issuer"`
+	Grants     []*TrustedIssuerGrant      `json:"grants,omitempty"`
+	Provenance []*TrustedIssuerProvenance `json:"provenance,omitempty"`
+}
+
+// TrustedIssuerList a serializable list of trusted issuers that can be
+// used to create or update a trusted issuer registry
+type TrustedIssuerList struct {
+	// Version a version number that increases with every update to the list
+	Version uint64           `json:"version"`
+	Issuers []*TrustedIssuer `json:"issuers"`
+}
+
+// TrustedIssuerChangeKind the kind of change made to an issuer's grant
+type TrustedIssuerChangeKind int
+
+func (k TrustedIssuerChangeKind) String() string {
+	switch k {
+	case TrustedIssuerChangeAdded:
+		return "Added"
+	case TrustedIssuerChangeRemoved:
+		return "Removed"
+	default:
+		return "Modified"
+	}
+}
+
+// TrustedIssuerChange a change to an issuer's authority between two trusted issuer lists
+type TrustedIssuerChange struct {
+	Kind           TrustedIssuerChangeKind
+	Issuer         string
+	CredentialType string
+	Before         *TrustedIssuerGrant
+	After          *TrustedIssuerGrant
+}
+
+// trustedIssuerSignature a detached signature over an encoded trusted issuer list
+type trustedIssuerSignature struct {
+	Signer    string `json:"signer"`
+	Signature string `json:"signature"`
+}
+
+// DecodeTrustedIssuerList decodes a json encoded trusted issuer list
+func DecodeTrustedIssuerList(encodedList []byte) (*TrustedIssuerList, error) {
+	var list TrustedIssuerList
+	return &list, json.Unmarshal(encodedList, &list)
+}
+
+// VerifyTrustedIssuerList verifies a detached signature over an encoded trusted issuer list was created
+// by one of the governance keys, before decoding it
+func VerifyTrustedIssuerList(encodedList, signature []byte, governance ...*signing.PublicKey) (*TrustedIssuerList, error) {
+	var detached trustedIssuerSignature
+
+	err := json.Unmarshal(signature, &detached)
+	if err != nil {
+		return nil, ErrTrustedIssuerSignature
+	}
+
+	signatureBytes, err := hex.DecodeString(detached.Signature)
+	if err != nil || len(signatureBytes) != ed25519.SignatureSize {
+		return nil, ErrTrustedIssuerSignature
+	}
+
+	governed := slices.ContainsFunc(governance, func(key *signing.PublicKey) bool {
+		return key.String() == detached.Signer
+	})
+
+	if !governed {
+		return nil, ErrTrustedIssuerSignature
+	}
+
+	signer := signing.FromAddress(detached.Signer)
+	if signer == nil {
+		return nil, ErrTrustedIssuerSignature
+	}
+
+	// signing keys are encoded with a leading key type
+	if !ed25519.Verify(ed25519.PublicKey(signer.Bytes()[1:]), encodedList, signatureBytes) {
+		return nil, ErrTrustedIssuerSignature
+	}
+
+	return DecodeTrustedIssuerList(encodedList)
+}
+
+// Encode encodes the list as json. Issuers and grants are sorted so lists with the same contents
+// are always encoded identically
+func (l *TrustedIssuerList) Encode() ([]byte, error) {
+	sort.Slice(l.Issuers, func(i, j int) bool {
+		return l.Issuers[i].Issuer < l.Issuers[j].Issuer
+	})
+
+	for _, issuer := range l.Issuers {
+		sort.SliceStable(issuer.Grants, func(i, j int) bool {
+			if issuer.Grants[i].CredentialType != issuer.Grants[j].CredentialType {
+				return issuer.Grants[i].CredentialType < issuer.Grants[j].CredentialType
+			}
+			return issuer.Grants[i].Granted.Before(issuer.Grants[j].Granted)
+		})
+	}
+
+	return json.MarshalIndent(l, "", "  ")
+}
+
+// Sign encodes the list and creates a detached signature over the encoded list with a governance key
+func (l *TrustedIssuerList) Sign(signer Signer, governanceKey *signing.PublicKey) ([]byte, []byte, error) {
+	encodedList, err := l.Encode()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signature, err := signer.KeychainSign(governanceKey, encodedList)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encodedSignature, err := json.Marshal(&trustedIssuerSignature{
+		Signer:    governanceKey.String(),
+		Signature: hex.EncodeToString(signature),
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return encodedList, encodedSignature, nil
+}
+
+// Registry creates a trusted issuer registry from the list
+func (l *TrustedIssuerList) Registry() (*TrustedIssuerRegistry, error) {
+	registry := NewTrustedIssuerRegistry()
+	return registry, l.ApplyTo(registry)
+}
+
+// ApplyTo adds the issuers and grants in the list to an existing registry,
+// such as one of the default production or sandbox registries
+func (l *TrustedIssuerList) ApplyTo(registry *TrustedIssuerRegistry) error {
+	for _, issuer := range l.Issuers {
+		address, err := DecodeAddress(issuer.Issuer)
+		if err != nil {
+			return err
+		}
+
+		registry.AddIssuer(address)
+
+		for _, grant := range issuer.Grants {
+			err = registry.GrantAuthority(address, grant.CredentialType, grant.Granted, grant.Revoked)
+			if err != nil {
+				return err
+			}
+		}
+
+		registry.mu.Lock()
+		registry.trusted.provenance(issuer.Issuer, issuer.Provenance...)
+		registry.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Merge merges the issuers, grants and provenance of other lists into the list
+func (l *TrustedIssuerList) Merge(lists ...*TrustedIssuerList) {
+	for _, list := range lists {
+		for _, issuer := range list.Issuers {
+			for _, grant := range issuer.Grants {
+				l.grant(issuer.Issuer, grant.CredentialType, grant.Granted, grant.Revoked)
+			}
+
+			l.provenance(issuer.Issuer, issuer.Provenance...)
+		}
+	}
+}
+
+// Diff returns the changes to issuers authority made by the updated list
+func (l *TrustedIssuerList) Diff(updated *TrustedIssuerList) []*TrustedIssuerChange {
+	before := l.grants()
+	after := updated.grants()
+
+	var changes []*TrustedIssuerChange
+
+	for key, grant := range before {
+		updatedGrant, ok := after[key]
+
+		if !ok {
+			changes = append(changes, &TrustedIssuerChange{
+				Kind:           TrustedIssuerChangeRemoved,
+				Issuer:         key.issuer,
+				CredentialType: key.credentialType,
+				Before:         grant,
+			})
+			continue
+		}
+
+		if !grant.equal(updatedGrant) {
+			changes = append(changes, &TrustedIssuerChange{
+				Kind:           TrustedIssuerChangeModified,
+				Issuer:         key.issuer,
+				CredentialType: key.credentialType,
+				Before:         grant,
+				After:          updatedGrant,
+			})
+		}
+	}
+
+	for key, grant := range after {
+		_, ok := before[key]
+		if !ok {
+			changes = append(changes, &TrustedIssuerChange{
+				Kind:           TrustedIssuerChangeAdded,
+				Issuer:         key.issuer,
+				CredentialType: key.credentialType,
+				After:          grant,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Issuer != changes[j].Issuer {
+			return changes[i].Issuer < changes[j].Issuer
+		}
+		return changes[i].CredentialType < changes[j].CredentialType
+	})
+
+	return changes
+}
+
+type trustedIssuerGrantKey struct {
+	issuer         string
+	credentialType string
+	granted        int64
+}
+
+func (l *TrustedIssuerList) grants() map[trustedIssuerGrantKey]*TrustedIssuerGrant {
+	grants := make(map[trustedIssuerGrantKey]*TrustedIssuerGrant)
+
+	for _, issuer := range l.Issuers {
+		for _, grant := range issuer.Grants {
+			grants[trustedIssuerGrantKey{
+				issuer:         issuer.Issuer,
+				credentialType: grant.CredentialType,
+				granted:        grant.Granted.Unix(),
+			}] = grant
+		}
+	}
+
+	return grants
+}
+
+func (g *TrustedIssuerGrant) equal(other *TrustedIssuerGrant) bool {
+	if g.Revoked == nil || other.Revoked == nil {
+		return g.Revoked == nil && other.Revoked == nil
+	}
+
+	return g.Revoked.Unix() == other.Revoked.Unix()
+}
+
+// record records an issuer being added to the list
+func (l *TrustedIssuerList) record(issuer string) *TrustedIssuer {
+	for _, trusted := range l.Issuers {
+		if trusted.Issuer == issuer {
+			return trusted
+		}
+	}
+
+	trusted := &TrustedIssuer{
+		Issuer: issuer,
+	}
+
+	l.Issuers = append(l.Issuers, trusted)
+
+	return trusted
+}
+
+// grant records authority being granted to an issuer
+func (l *TrustedIssuerList) grant(issuer, credentialType string, granted time.Time, revoked *time.Time) {
+	trusted := l.record(issuer)
+
+	for _, existing := range trusted.Grants {
+		if existing.CredentialType == credentialType && existing.Granted.Unix() == granted.Unix() {
+			existing.Revoked = revoked
+			return
+		}
+	}
+
+	trusted.Grants = append(trusted.Grants, &TrustedIssuerGrant{
+		CredentialType: credentialType,
+		Granted:        granted,
+		Revoked:        revoked,
+	})
+}
+
+// revoke records an issuer's open grants for a credential type being revoked
+func (l *TrustedIssuerList) revoke(issuer, credentialType string, revoked time.Time) {
+	trusted := l.record(issuer)
+
+	for _, existing := range trusted.Grants {
+		if existing.CredentialType == credentialType && existing.Revoked == nil {
+			existing.Revoked = &revoked
+		}
+	}
+}
+
+// provenance records where an issuer was imported from
+func (l *TrustedIssuerList) provenance(issuer string, provenance ...*TrustedIssuerProvenance) {
+	trusted := l.record(issuer)
+
+	for _, p := range provenance {
+		duplicate := slices.ContainsFunc(trusted.Provenance, func(existing *TrustedIssuerProvenance) bool {
+			return existing.Format == p.Format && existing.Source == p.Source && existing.Reference == p.Reference
+		})
+
+		if !duplicate {
+			trusted.Provenance = append(trusted.Provenance, p)
+		}
+	}
+}
+
+// remove records an issuer being removed from the list
+func (l *TrustedIssuerList) remove(issuer string) {
+	l.Issuers = slices.DeleteFunc(l.Issuers, func(trusted *TrustedIssuer) bool {
+		return trusted.Issuer == issuer
+	})
+}
+
+// copy returns a deep copy of the list
+func (l *TrustedIssuerList) copy() *TrustedIssuerList {
+	var buf bytes.Buffer
+
+	// the list only contains json encodable values
+	_ = json.NewEncoder(&buf).Encode(l)
+
+	var list TrustedIssuerList
+	_ = json.NewDecoder(&buf).Decode(&list)
+
+	return &list
+}
@@ -0,0 +1,277 @@
+package credential
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joinself/self-go-sdk/keypair/signing"
+)
+
+var (
+	// ErrTrustedIssuerListVersion returned when loading a trusted issuer list older than the highest version
+	// loaded, or a list with the same version as the highest version loaded but different content
+	ErrTrustedIssuerListVersion = errors.New("trusted issuer list version is older than or conflicts with the loaded list")
+)
+
+// TrustedIssuerLoaderConfig configures how a trusted issuer list is loaded
+type TrustedIssuerLoaderConfig struct {
+	// Path the path of the encoded trusted issuer list. The detached signature is loaded from Path + ".sig"
+	Path string
+	// VersionPath the path the version and digest of the highest version of the list loaded are persisted to,
+	// so older lists are rejected after a restart. defaults to Path + ".version"
+	VersionPath string
+	// Governance the keys that are trusted to sign the list
+	Governance []*signing.PublicKey
+	// Base optionally creates the registry the list is applied to, such as ProductionTrustedIssuerRegistry.
+	// defaults to an empty registry
+	Base func() *TrustedIssuerRegistry
+	// OnReload an optional callback invoked with the changes made by a reloaded list
+	OnReload func(list *TrustedIssuerList, changes []*TrustedIssuerChange)
+	// OnError an optional callback invoked when a watched list fails to reload.
+	// the previously loaded registry remains in use
+	OnError func(err error)
+}
+
+// TrustedIssuerLoader loads a signed trusted issuer list from a file and
+// atomically swaps the registry whenever the file is updated
+type TrustedIssuerLoader struct {
+	config   TrustedIssuerLoaderConfig
+	loaded   atomic.Pointer[loadedTrustedIssuers]
+	modified time.Time
+	size     int64
+	mu       sync.Mutex
+	done     chan struct{}
+}
+
+type loadedTrustedIssuers struct {
+	list     *TrustedIssuerList
+	registry *TrustedIssuerRegistry
+}
+
+// trustedIssuerListVersion the highest version of a trusted issuer list that has been loaded
+type trustedIssuerListVersion struct {
+	Version uint64 `json:"version"`
+	Digest  []byte `json:"digest"`
+}
+
+// NewTrustedIssuerLoader creates a loader and loads the trusted issuer list
+func NewTrustedIssuerLoader(config TrustedIssuerLoaderConfig) (*TrustedIssuerLoader, error) {
+	l := &TrustedIssuerLoader{
+		config: config,
+		done:   make(chan struct{}),
+	}
+
+	_, err := l.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Registry returns the currently loaded registry
+func (l *TrustedIssuerLoader) Registry() *TrustedIssuerRegistry {
+	return l.loaded.Load().registry
+}
+
+// List returns a copy of the currently loaded trusted issuer list
+func (l *TrustedIssuerLoader) List() *TrustedIssuerList {
+	return l.loaded.Load().list.copy()
+}
+
+// Reload loads and verifies the trusted issuer list, replacing the current registry.
+// Lists with a lower version than the highest version loaded, or with the same version
+// but different content, are rejected. Returns the changes made by the reloaded list
+func (l *TrustedIssuerLoader) Reload() ([]*TrustedIssuerChange, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info, err := os.Stat(l.config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedList, err := os.ReadFile(l.config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := os.ReadFile(l.config.Path + ".sig")
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := VerifyTrustedIssuerList(encodedList, signature, l.config.Governance...)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(encodedList)
+
+	seen, err := l.versionLoad()
+	if err != nil {
+		return nil, err
+	}
+
+	current := l.loaded.Load()
+
+	if seen != nil {
+		if list.Version < seen.Version {
+			return nil, ErrTrustedIssuerListVersion
+		}
+
+		if list.Version == seen.Version {
+			if !bytes.Equal(digest[:], seen.Digest) {
+				return nil, ErrTrustedIssuerListVersion
+			}
+
+			if current != nil {
+				// the list has not changed
+				l.modified = info.ModTime()
+				l.size = info.Size()
+
+				return nil, nil
+			}
+		}
+	}
+
+	registry := NewTrustedIssuerRegistry()
+	if l.config.Base != nil {
+		registry = l.config.Base()
+	}
+
+	err = list.ApplyTo(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*TrustedIssuerChange
+
+	if current != nil {
+		changes = current.list.Diff(list)
+	} else {
+		changes = (&TrustedIssuerList{}).Diff(list)
+	}
+
+	err = l.versionStore(&trustedIssuerListVersion{
+		Version: list.Version,
+		Digest:  digest[:],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l.loaded.Store(&loadedTrustedIssuers{
+		list:     list,
+		registry: registry,
+	})
+
+	l.modified = info.ModTime()
+	l.size = info.Size()
+
+	return changes, nil
+}
+
+// Watch polls the trusted issuer list for changes, reloading it when it is modified,
+// until the loader is closed
+func (l *TrustedIssuerLoader) Watch(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-l.done:
+				return
+			case <-ticker.C:
+				if !l.changed() {
+					continue
+				}
+
+				changes, err := l.Reload()
+				if err != nil {
+					if l.config.OnError != nil {
+						l.config.OnError(err)
+					}
+					continue
+				}
+
+				if l.config.OnReload != nil {
+					l.config.OnReload(l.List(), changes)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops watching the trusted issuer list
+func (l *TrustedIssuerLoader) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-l.done:
+	default:
+		close(l.done)
+	}
+}
+
+// changed returns true if the list has been modified since it was last loaded
+func (l *TrustedIssuerLoader) changed() bool {
+	info, err := os.Stat(l.config.Path)
+	if err != nil {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return !info.ModTime().Equal(l.modified) || info.Size() != l.size
+}
+
+// versionLoad loads the highest version of the list that has been loaded. Returns nil if no list has been loaded
+func (l *TrustedIssuerLoader) versionLoad() (*trustedIssuerListVersion, error) {
+	encodedVersion, err := os.ReadFile(l.versionPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var version trustedIssuerListVersion
+
+	return &version, json.Unmarshal(encodedVersion, &version)
+}
+
+// versionStore persists the version of a loaded list, replacing the previous version atomically
+func (l *TrustedIssuerLoader) versionStore(version *trustedIssuerListVersion) error {
+	encodedVersion, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+
+	path := l.versionPath()
+
+	err = os.WriteFile(path+".tmp", encodedVersion, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+func (l *TrustedIssuerLoader) versionPath() string {
+	if l.config.VersionPath != "" {
+		return l.config.VersionPath
+	}
+
+	return l.config.Path + ".version"
+}
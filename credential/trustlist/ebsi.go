@@ -0,0 +1,275 @@
+package trustlist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+)
+
+var (
+	// ErrInvalidAccreditation returned when an issuer's accreditation cannot be decoded
+	ErrInvalidAccreditation = errors.New("issuer accreditation is invalid")
+)
+
+// types that are common to all accreditations and are not imported as credential types
+var ebsiBaseTypes = []string{
+	"VerifiableCredential",
+	"VerifiableAttestation",
+}
+
+type ebsiRegistry struct {
+	Items []*ebsiIssuer `json:"items"`
+}
+
+type ebsiIssuer struct {
+	DID        string           `json:"did"`
+	Attributes []*ebsiAttribute `json:"attributes"`
+}
+
+type ebsiAttribute struct {
+	Hash       string          `json:"hash"`
+	Body       json.RawMessage `json:"body"`
+	IssuerType string          `json:"issuerType"`
+	TAO        string          `json:"tao"`
+}
+
+type ebsiAccreditation struct {
+	Issuer         json.RawMessage `json:"issuer"`
+	ValidFrom      time.Time       `json:"validFrom"`
+	ValidUntil     *time.Time      `json:"validUntil"`
+	IssuanceDate   time.Time       `json:"issuanceDate"`
+	ExpirationDate *time.Time      `json:"expirationDate"`
+	Subject        struct {
+		ID            string `json:"id"`
+		AccreditedFor []struct {
+			SchemaID string   `json:"schemaId"`
+			Types    []string `json:"types"`
+		} `json:"accreditedFor"`
+	} `json:"credentialSubject"`
+}
+
+// ImportEBSI imports the issuers of an EBSI style trusted issuer registry. The registry may be
+// a single issuer, a list of issuers, or a page of issuers as returned by the registry's api.
+// Each issuer attribute is an accreditation, either as a jwt or json, and the types it is accredited
+// for are imported as grants for it's validity period. Accreditations must be verified by the Verify
+// option and must accredit the issuer they are listed under, otherwise they are skipped. Revoked
+// attributes are skipped
+func ImportEBSI(path string, opts ...Options) (*credential.TrustedIssuerList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	issuers, err := ebsiIssuers(data)
+	if err != nil {
+		return nil, err
+	}
+
+	o := options(opts)
+	if o.Verify == nil {
+		return nil, ErrNoVerifier
+	}
+
+	imported := time.Now()
+
+	list := &credential.TrustedIssuerList{}
+
+	for _, issuer := range issuers {
+		for _, attribute := range issuer.Attributes {
+			reference := issuer.DID + "#" + attribute.Hash
+
+			if attribute.IssuerType == "Revoked" {
+				continue
+			}
+
+			accreditation, err := attribute.accreditation()
+			if err != nil {
+				o.skip(reference, err)
+				continue
+			}
+
+			err = o.verify(FormatEBSI, attribute.signed())
+			if err != nil {
+				o.skip(reference, err)
+				continue
+			}
+
+			did := issuer.DID
+			if did == "" {
+				did = accreditation.Subject.ID
+			}
+
+			// an accreditation listed under an issuer may have been issued to someone else
+			if accreditation.Subject.ID != did {
+				o.skip(reference, fmt.Errorf("%w: accredits %s", ErrInvalidAccreditation, accreditation.Subject.ID))
+				continue
+			}
+
+			grants := accreditation.grants(o.CredentialTypes)
+			if len(grants) == 0 {
+				o.skip(reference, ErrInvalidAccreditation)
+				continue
+			}
+
+			operator := attribute.TAO
+			if operator == "" {
+				operator = accreditation.issuer()
+			}
+
+			err = o.add(list, did, grants, &credential.TrustedIssuerProvenance{
+				Format:    FormatEBSI,
+				Source:    path,
+				Operator:  operator,
+				Reference: reference,
+				Imported:  imported,
+			})
+
+			if err != nil {
+				o.skip(reference, err)
+			}
+		}
+	}
+
+	return list, nil
+}
+
+func ebsiIssuers(data []byte) ([]*ebsiIssuer, error) {
+	data = bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(data, []byte("[")) {
+		var issuers []*ebsiIssuer
+		return issuers, json.Unmarshal(data, &issuers)
+	}
+
+	var registry ebsiRegistry
+
+	err := json.Unmarshal(data, &registry)
+	if err != nil {
+		return nil, err
+	}
+
+	if registry.Items != nil {
+		return registry.Items, nil
+	}
+
+	var issuer ebsiIssuer
+
+	return []*ebsiIssuer{&issuer}, json.Unmarshal(data, &issuer)
+}
+
+// signed returns the attribute's signed body, which is either a jwt or json encoded credential
+func (a *ebsiAttribute) signed() []byte {
+	var encoded string
+
+	if json.Unmarshal(a.Body, &encoded) == nil {
+		return []byte(encoded)
+	}
+
+	return a.Body
+}
+
+// accreditation decodes the attribute's body, which is either a jwt or json encoded credential
+func (a *ebsiAttribute) accreditation() (*ebsiAccreditation, error) {
+	payload := []byte(a.Body)
+
+	var encoded string
+
+	if json.Unmarshal(a.Body, &encoded) == nil {
+		parts := strings.Split(encoded, ".")
+		if len(parts) != 3 {
+			return nil, ErrInvalidAccreditation
+		}
+
+		var err error
+
+		payload, err = base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, ErrInvalidAccreditation
+		}
+	}
+
+	var claims struct {
+		VC *ebsiAccreditation `json:"vc"`
+	}
+
+	err := json.Unmarshal(payload, &claims)
+	if err != nil {
+		return nil, ErrInvalidAccreditation
+	}
+
+	if claims.VC != nil {
+		return claims.VC, nil
+	}
+
+	var accreditation ebsiAccreditation
+
+	err = json.Unmarshal(payload, &accreditation)
+	if err != nil {
+		return nil, ErrInvalidAccreditation
+	}
+
+	return &accreditation, nil
+}
+
+// issuer returns the did of the accreditation's issuer
+func (a *ebsiAccreditation) issuer() string {
+	var issuer string
+
+	if json.Unmarshal(a.Issuer, &issuer) == nil {
+		return issuer
+	}
+
+	var issuerObject struct {
+		ID string `json:"id"`
+	}
+
+	_ = json.Unmarshal(a.Issuer, &issuerObject)
+
+	return issuerObject.ID
+}
+
+// grants returns a grant for each type the issuer is accredited for
+func (a *ebsiAccreditation) grants(credentialTypes map[string][]string) []*credential.TrustedIssuerGrant {
+	granted := a.ValidFrom
+	if granted.IsZero() {
+		granted = a.IssuanceDate
+	}
+
+	revoked := a.ValidUntil
+	if revoked == nil {
+		revoked = a.ExpirationDate
+	}
+
+	var grants []*credential.TrustedIssuerGrant
+
+	for _, accreditedFor := range a.Subject.AccreditedFor {
+		for _, accreditedType := range accreditedFor.Types {
+			if slices.Contains(ebsiBaseTypes, accreditedType) {
+				continue
+			}
+
+			mapped, ok := credentialTypes[accreditedType]
+			if !ok {
+				mapped = []string{accreditedType}
+			}
+
+			for _, credentialType := range mapped {
+				grants = append(grants, &credential.TrustedIssuerGrant{
+					CredentialType: credentialType,
+					Granted:        granted,
+					Revoked:        revoked,
+				})
+			}
+		}
+	}
+
+	return grants
+}
@@ -0,0 +1,236 @@
+package trustlist
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/keypair"
+	"github.com/joinself/self-go-sdk/keypair/signing"
+)
+
+var (
+	// ErrNoIssuerIdentity returned when a service's digital identity cannot be mapped to an issuer address
+	ErrNoIssuerIdentity = errors.New("service has no ed25519 certificate or did digital identity")
+	// ErrUnmappedServiceType returned when a service's type is not mapped to any credential types
+	ErrUnmappedServiceType = errors.New("service type is not mapped to a credential type")
+)
+
+// statuses that grant a service authority, including the statuses of older versions of the specification
+var etsiGrantedStatuses = map[string]bool{
+	"http://uri.etsi.org/TrstSvc/TrustedList/Svcstatus/granted":                   true,
+	"http://uri.etsi.org/TrstSvc/TrustedList/Svcstatus/recognisedatnationallevel": true,
+	"http://uri.etsi.org/TrstSvc/Svcstatus/undersupervision":                      true,
+	"http://uri.etsi.org/TrstSvc/Svcstatus/supervisionincessation":                true,
+	"http://uri.etsi.org/TrstSvc/Svcstatus/accredited":                            true,
+}
+
+var didPattern = regexp.MustCompile(`did:[a-z0-9]+:[^\s<>"]+`)
+
+type etsiTrustedList struct {
+	SequenceNumber uint64         `xml:"SchemeInformation>TSLSequenceNumber"`
+	Operator       []etsiName     `xml:"SchemeInformation>SchemeOperatorName>Name"`
+	Providers      []etsiProvider `xml:"TrustServiceProviderList>TrustServiceProvider"`
+}
+
+type etsiName struct {
+	Lang  string `xml:"lang,attr"`
+	Value string `xml:",chardata"`
+}
+
+type etsiProvider struct {
+	Name     []etsiName    `xml:"TSPInformation>TSPName>Name"`
+	Services []etsiService `xml:"TSPServices>TSPService"`
+}
+
+type etsiService struct {
+	Information etsiServiceInformation   `xml:"ServiceInformation"`
+	History     []etsiServiceInformation `xml:"ServiceHistory>ServiceHistoryInstance"`
+}
+
+type etsiServiceInformation struct {
+	Type       string          `xml:"ServiceTypeIdentifier"`
+	Name       []etsiName      `xml:"ServiceName>Name"`
+	DigitalIDs []etsiDigitalID `xml:"ServiceDigitalIdentity>DigitalId"`
+	Status     string          `xml:"ServiceStatus"`
+	Starting   time.Time       `xml:"StatusStartingTime"`
+}
+
+type etsiDigitalID struct {
+	Certificate string `xml:"X509Certificate"`
+	Other       struct {
+		Value string `xml:",innerxml"`
+	} `xml:"Other"`
+}
+
+// ImportETSI imports the services of an ETSI TS 119 612 trusted list as trusted issuers. Services are
+// identified by an ed25519 X509 certificate, or a did held in an Other digital identity. Each granted
+// period in a service's status history is imported as a grant for the credential types it's service
+// type is mapped to. The list's signature must be verified by the Verify option before it is imported
+func ImportETSI(path string, opts ...Options) (*credential.TrustedIssuerList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	o := options(opts)
+
+	err = o.verify(FormatETSI, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var tsl etsiTrustedList
+
+	err = xml.Unmarshal(data, &tsl)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := time.Now()
+
+	list := &credential.TrustedIssuerList{
+		Version: tsl.SequenceNumber,
+	}
+
+	for _, provider := range tsl.Providers {
+		for _, service := range provider.Services {
+			reference := etsiNameOf(provider.Name) + "/" + etsiNameOf(service.Information.Name)
+
+			did, err := service.Information.issuer()
+			if err != nil {
+				o.skip(reference, err)
+				continue
+			}
+
+			grants := service.grants(o.CredentialTypes)
+			if len(grants) == 0 {
+				o.skip(reference, ErrUnmappedServiceType)
+				continue
+			}
+
+			err = o.add(list, did, grants, &credential.TrustedIssuerProvenance{
+				Format:    FormatETSI,
+				Source:    path,
+				Operator:  etsiNameOf(tsl.Operator),
+				Reference: reference,
+				Imported:  imported,
+			})
+
+			if err != nil {
+				o.skip(reference, err)
+			}
+		}
+	}
+
+	return list, nil
+}
+
+// issuer returns the did of the service's digital identity
+func (s *etsiServiceInformation) issuer() (string, error) {
+	for _, id := range s.DigitalIDs {
+		did := didPattern.FindString(id.Other.Value)
+		if did != "" {
+			return did, nil
+		}
+
+		if id.Certificate == "" {
+			continue
+		}
+
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(id.Certificate), ""))
+		if err != nil {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(der)
+		if err != nil {
+			continue
+		}
+
+		publicKey, ok := certificate.PublicKey.(ed25519.PublicKey)
+		if !ok {
+			continue
+		}
+
+		// signing keys are encoded with a leading key type
+		key := signing.FromBytes(append([]byte{byte(keypair.KeyTypeSigning)}, publicKey...))
+		if key == nil {
+			continue
+		}
+
+		return credential.AddressKey(key).String(), nil
+	}
+
+	return "", ErrNoIssuerIdentity
+}
+
+// grants returns the periods the service was granted authority for each of it's mapped credential types
+func (s *etsiService) grants(credentialTypes map[string][]string) []*credential.TrustedIssuerGrant {
+	history := append([]etsiServiceInformation{s.Information}, s.History...)
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Starting.Before(history[j].Starting)
+	})
+
+	var grants []*credential.TrustedIssuerGrant
+
+	open := make(map[string]*credential.TrustedIssuerGrant)
+
+	for _, status := range history {
+		granted := make(map[string]bool)
+
+		if etsiGrantedStatuses[status.Status] {
+			for _, credentialType := range credentialTypes[status.Type] {
+				granted[credentialType] = true
+			}
+		}
+
+		for credentialType, grant := range open {
+			if !granted[credentialType] {
+				revoked := status.Starting
+				grant.Revoked = &revoked
+				delete(open, credentialType)
+			}
+		}
+
+		for credentialType := range granted {
+			if open[credentialType] != nil {
+				continue
+			}
+
+			grant := &credential.TrustedIssuerGrant{
+				CredentialType: credentialType,
+				Granted:        status.Starting,
+			}
+
+			open[credentialType] = grant
+			grants = append(grants, grant)
+		}
+	}
+
+	return grants
+}
+
+// etsiNameOf returns the english version of a multilingual name
+func etsiNameOf(names []etsiName) string {
+	for _, name := range names {
+		if name.Lang == "en" {
+			return strings.TrimSpace(name.Value)
+		}
+	}
+
+	if len(names) > 0 {
+		return strings.TrimSpace(names[0].Value)
+	}
+
+	return ""
+}
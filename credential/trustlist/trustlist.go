@@ -0,0 +1,104 @@
+// Package trustlist imports issuers from external trust lists, such as ETSI TS 119 612
+// trusted lists and EBSI trusted issuer registries, so they can be merged into a
+// credential.TrustedIssuerRegistry
+package trustlist
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joinself/self-go-sdk/credential"
+)
+
+const (
+	// FormatETSI the format of ETSI TS 119 612 trusted lists
+	FormatETSI = "etsi-ts-119-612"
+	// FormatEBSI the format of EBSI trusted issuer registries
+	FormatEBSI = "ebsi"
+)
+
+var (
+	// ErrNoVerifier returned when importing a trust list without a verifier
+	ErrNoVerifier = errors.New("trust list import requires a verifier")
+	// ErrUnverified returned when the signature of a trust list, or an entry in it, is rejected by the verifier
+	ErrUnverified = errors.New("trust list signature could not be verified")
+)
+
+// Options configures how entries in a trust list are mapped to trusted issuers
+type Options struct {
+	// CredentialTypes maps the service types of ETSI trusted lists, or the accredited types
+	// of EBSI registries, to credential types. ETSI services with unmapped service types are
+	// skipped, where unmapped EBSI types are imported as they are
+	CredentialTypes map[string][]string
+	// Resolve resolves an issuer's did to a credential address. defaults to credential.DecodeAddress
+	Resolve func(did string) (*credential.Address, error)
+	// Verify verifies the signature of a trust list, and is required as this package does not verify
+	// signatures itself. It is called with the format and the signed data, which is the whole document
+	// of ETSI trusted lists, and each accreditation of EBSI registries, as a jwt or json credential
+	Verify func(format string, signed []byte) error
+	// OnSkip an optional callback invoked when an entry in the trust list cannot be imported
+	OnSkip func(reference string, err error)
+}
+
+// Merge merges imported trust lists into a trusted issuer registry,
+// recording the provenance of each issuer
+func Merge(registry *credential.TrustedIssuerRegistry, lists ...*credential.TrustedIssuerList) error {
+	merged := &credential.TrustedIssuerList{}
+	merged.Merge(lists...)
+
+	return merged.ApplyTo(registry)
+}
+
+func options(opts []Options) Options {
+	var o Options
+
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	if o.Resolve == nil {
+		o.Resolve = credential.DecodeAddress
+	}
+
+	return o
+}
+
+// verify checks signed data with the caller's verifier
+func (o *Options) verify(format string, signed []byte) error {
+	if o.Verify == nil {
+		return ErrNoVerifier
+	}
+
+	err := o.Verify(format, signed)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnverified, err)
+	}
+
+	return nil
+}
+
+func (o *Options) skip(reference string, err error) {
+	if o.OnSkip != nil {
+		o.OnSkip(reference, err)
+	}
+}
+
+// add resolves an issuer's address and merges it's grants into the list
+func (o *Options) add(list *credential.TrustedIssuerList, did string, grants []*credential.TrustedIssuerGrant, provenance *credential.TrustedIssuerProvenance) error {
+	address, err := o.Resolve(did)
+	if err != nil {
+		return err
+	}
+
+	list.Merge(&credential.TrustedIssuerList{
+		Issuers: []*credential.TrustedIssuer{
+			{
+				Issuer:     address.String(),
+				Grants:     grants,
+				Provenance: []*credential.TrustedIssuerProvenance{provenance},
+			},
+		},
+	})
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package w3c
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// canonicalize encodes a value using the json canonicalization scheme (RFC 8785)
+func canonicalize(value interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+
+	err = json.Unmarshal(encoded, &decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	err = canonicalizeValue(&buf, decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func canonicalizeValue(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case float64:
+		number, err := canonicalNumber(v)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString(number)
+	case string:
+		canonicalString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			err := canonicalizeValue(buf, item)
+			if err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		// properties are sorted by their utf-16 code units
+		sort.Slice(keys, func(i, j int) bool {
+			a := utf16.Encode([]rune(keys[i]))
+			b := utf16.Encode([]rune(keys[j]))
+
+			for k := 0; k < len(a) && k < len(b); k++ {
+				if a[k] != b[k] {
+					return a[k] < b[k]
+				}
+			}
+
+			return len(a) < len(b)
+		})
+
+		buf.WriteByte('{')
+
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			canonicalString(buf, key)
+			buf.WriteByte(':')
+
+			err := canonicalizeValue(buf, v[key])
+			if err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unsupported json value %T", value)
+	}
+
+	return nil
+}
+
+// canonicalNumber serializes a number as defined by ECMAScript's Number.prototype.toString
+func canonicalNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", errors.New("json numbers cannot be NaN or Infinity")
+	}
+
+	if f == 0 {
+		return "0", nil
+	}
+
+	abs := math.Abs(f)
+
+	if abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+
+	// exponential form, using the shortest representation
+	formatted := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exponent, _ := strings.Cut(formatted, "e")
+
+	exp, err := strconv.Atoi(exponent)
+	if err != nil {
+		return "", err
+	}
+
+	if exp > 0 {
+		return mantissa + "e+" + strconv.Itoa(exp), nil
+	}
+
+	return mantissa + "e" + strconv.Itoa(exp), nil
+}
+
+func canonicalString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+
+	buf.WriteByte('"')
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// encodeMultibase encodes data as a base58btc multibase string
+func encodeMultibase(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var encoded []byte
+
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+
+		encoded = append(encoded, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+
+	return "z" + string(encoded)
+}
+
+// decodeMultibase decodes a base58btc multibase string
+func decodeMultibase(encoded string) ([]byte, error) {
+	if !strings.HasPrefix(encoded, "z") {
+		return nil, errors.New("multibase value is not base58btc encoded")
+	}
+
+	encoded = encoded[1:]
+
+	n := new(big.Int)
+	radix := big.NewInt(58)
+
+	for _, r := range encoded {
+		index := strings.IndexRune(base58Alphabet, r)
+		if index < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", r)
+		}
+
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(index)))
+	}
+
+	var leading int
+
+	for _, r := range encoded {
+		if r != rune(base58Alphabet[0]) {
+			break
+		}
+
+		leading++
+	}
+
+	return append(make([]byte, leading), n.Bytes()...), nil
+}
@@ -0,0 +1,198 @@
+package w3c
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/identity"
+	"github.com/joinself/self-go-sdk/keypair/signing"
+)
+
+const (
+	// ProofTypeDataIntegrity the type of data integrity proofs
+	ProofTypeDataIntegrity = "DataIntegrityProof"
+	// CryptosuiteEddsaJcs2022 the eddsa-jcs-2022 cryptosuite
+	CryptosuiteEddsaJcs2022 = "eddsa-jcs-2022"
+	// ProofPurposeAssertion the proof purpose of credentials
+	ProofPurposeAssertion = "assertionMethod"
+	// ProofPurposeAuthentication the proof purpose of presentations
+	ProofPurposeAuthentication = "authentication"
+	// MaxClockSkew the maximum period a proof's created timestamp can be ahead of the verifier's clock
+	MaxClockSkew = time.Minute * 5
+)
+
+var (
+	// ErrInvalidProof returned when a document's proof is missing, malformed or has an invalid signature
+	ErrInvalidProof = errors.New("invalid data integrity proof")
+	// ErrUnauthorizedSigner returned when a document was signed by a key that is not authorized by the issuer or holder
+	ErrUnauthorizedSigner = errors.New("document signer not authorized")
+)
+
+// Signer signs payloads with a key held by an account
+type Signer interface {
+	KeychainSign(address *signing.PublicKey, payload []byte) ([]byte, error)
+}
+
+// Resolver resolves the identity document of an aure address, such as Account.IdentityResolve
+type Resolver func(address *signing.PublicKey) (*identity.Document, error)
+
+// Proof a data integrity proof
+type Proof struct {
+	Context            []string `json:"@context,omitempty"`
+	Type               string   `json:"type"`
+	Cryptosuite        string   `json:"cryptosuite"`
+	Created            string   `json:"created"`
+	VerificationMethod string   `json:"verificationMethod"`
+	ProofPurpose       string   `json:"proofPurpose"`
+	Challenge          string   `json:"challenge,omitempty"`
+	Domain             string   `json:"domain,omitempty"`
+	ProofValue         string   `json:"proofValue,omitempty"`
+}
+
+// createProof creates an eddsa-jcs-2022 proof over an unsecured document
+func createProof(signer Signer, document interface{}, context []string, verificationMethod *credential.Address, options *Proof) (*Proof, error) {
+	signingKey := verificationMethod.SigningKey()
+	if signingKey == nil {
+		return nil, errors.New("verification method does not specify a signing key")
+	}
+
+	proof := *options
+	proof.Type = ProofTypeDataIntegrity
+	proof.Cryptosuite = CryptosuiteEddsaJcs2022
+	proof.VerificationMethod = verificationMethod.String()
+	proof.Context = context
+	proof.ProofValue = ""
+
+	hashData, err := proofHashData(document, &proof)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signer.KeychainSign(signingKey, hashData)
+	if err != nil {
+		return nil, err
+	}
+
+	proof.Context = nil
+	proof.ProofValue = encodeMultibase(signature)
+
+	return &proof, nil
+}
+
+// verifyProof verifies an eddsa-jcs-2022 proof with the given purpose over an unsecured document,
+// and that the verification method is a key of the controller with one of the required roles.
+// did:key controllers can only be verified by their own key
+func verifyProof(document interface{}, context []string, proof *Proof, purpose string, controller *credential.Address, resolve Resolver, roles ...identity.Role) error {
+	if proof == nil {
+		return fmt.Errorf("%w: proof missing", ErrInvalidProof)
+	}
+
+	if proof.Type != ProofTypeDataIntegrity || proof.Cryptosuite != CryptosuiteEddsaJcs2022 {
+		return fmt.Errorf("%w: unsupported proof type %s %s", ErrInvalidProof, proof.Type, proof.Cryptosuite)
+	}
+
+	if proof.ProofPurpose != purpose {
+		return fmt.Errorf("%w: unexpected proof purpose %s", ErrInvalidProof, proof.ProofPurpose)
+	}
+
+	verificationMethod, err := credential.DecodeAddress(proof.VerificationMethod)
+	if err != nil {
+		return fmt.Errorf("%w: invalid verification method", ErrInvalidProof)
+	}
+
+	signingKey := verificationMethod.SigningKey()
+	if signingKey == nil {
+		signingKey = verificationMethod.Address()
+	}
+
+	if !verificationMethod.Address().Matches(controller.Address()) {
+		return fmt.Errorf("%w: verification method does not belong to %s", ErrUnauthorizedSigner, controller.String())
+	}
+
+	switch controller.Method() {
+	case credential.MethodKey:
+		// a did:key address has no other keys, so the proof must be signed by the address itself
+		if !signingKey.Matches(controller.Address()) {
+			return fmt.Errorf("%w: verification method does not belong to %s", ErrUnauthorizedSigner, controller.String())
+		}
+	case credential.MethodAure:
+	default:
+		return fmt.Errorf("%w: unsupported address method", ErrUnauthorizedSigner)
+	}
+
+	signature, err := decodeMultibase(proof.ProofValue)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: invalid proof value", ErrInvalidProof)
+	}
+
+	unsigned := *proof
+	unsigned.Context = context
+	unsigned.ProofValue = ""
+
+	hashData, err := proofHashData(document, &unsigned)
+	if err != nil {
+		return err
+	}
+
+	// signing keys are encoded with a leading key type
+	publicKey := ed25519.PublicKey(signingKey.Bytes()[1:])
+
+	if !ed25519.Verify(publicKey, hashData, signature) {
+		return fmt.Errorf("%w: signature verification failed", ErrInvalidProof)
+	}
+
+	if controller.Method() != credential.MethodAure {
+		return nil
+	}
+
+	if resolve == nil {
+		return fmt.Errorf("%w: a resolver is required to verify aure addresses", ErrUnauthorizedSigner)
+	}
+
+	identityDocument, err := resolve(controller.Address())
+	if err != nil {
+		return err
+	}
+
+	created, err := time.Parse(time.RFC3339, proof.Created)
+	if err != nil {
+		return fmt.Errorf("%w: invalid created timestamp", ErrInvalidProof)
+	}
+
+	// created is controlled by the signer, so roles are checked at the time of
+	// verification rather than when the signer claims the proof was created
+	now := time.Now()
+
+	if created.After(now.Add(MaxClockSkew)) {
+		return fmt.Errorf("%w: created timestamp is in the future", ErrInvalidProof)
+	}
+
+	for _, role := range roles {
+		if identityDocument.HasRolesAt(signingKey, role, now) {
+			return nil
+		}
+	}
+
+	return ErrUnauthorizedSigner
+}
+
+func proofHashData(document interface{}, proof *Proof) ([]byte, error) {
+	canonicalProof, err := canonicalize(proof)
+	if err != nil {
+		return nil, err
+	}
+
+	canonicalDocument, err := canonicalize(document)
+	if err != nil {
+		return nil, err
+	}
+
+	proofHash := sha256.Sum256(canonicalProof)
+	documentHash := sha256.Sum256(canonicalDocument)
+
+	return append(proofHash[:], documentHash[:]...), nil
+}
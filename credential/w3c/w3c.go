@@ -0,0 +1,382 @@
+// Package w3c converts credentials and presentations to and from the W3C Verifiable
+// Credentials Data Model 2.0, secured with eddsa-jcs-2022 data integrity proofs
+package w3c
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/identity"
+)
+
+const (
+	// ContextV2 the json-ld context of the verifiable credentials data model 2.0
+	ContextV2 = "https://www.w3.org/ns/credentials/v2"
+	// TypeVerifiableCredential the base type of all credentials
+	TypeVerifiableCredential = "VerifiableCredential"
+	// TypeVerifiablePresentation the base type of all presentations
+	TypeVerifiablePresentation = "VerifiablePresentation"
+)
+
+// Credential a credential in the W3C verifiable credentials data model
+type Credential struct {
+	Context           []string               `json:"@context"`
+	ID                string                 `json:"id,omitempty"`
+	Type              []string               `json:"type"`
+	Issuer            string                 `json:"issuer"`
+	ValidFrom         string                 `json:"validFrom,omitempty"`
+	ValidUntil        string                 `json:"validUntil,omitempty"`
+	CredentialSubject map[string]interface{} `json:"credentialSubject"`
+	Proof             *Proof                 `json:"proof,omitempty"`
+}
+
+// Presentation a presentation in the W3C verifiable credentials data model
+type Presentation struct {
+	Context              []string      `json:"@context"`
+	ID                   string        `json:"id,omitempty"`
+	Type                 []string      `json:"type"`
+	Holder               string        `json:"holder"`
+	VerifiableCredential []*Credential `json:"verifiableCredential,omitempty"`
+	Proof                *Proof        `json:"proof,omitempty"`
+}
+
+// FromCredential converts a verifiable credential to an unsecured W3C credential
+func FromCredential(verifiableCredential *credential.VerifiableCredential) (*Credential, error) {
+	claims, err := verifiableCredential.CredentialSubjectClaims()
+	if err != nil {
+		return nil, err
+	}
+
+	if claims == nil {
+		claims = make(map[string]interface{})
+	}
+
+	claims["id"] = verifiableCredential.CredentialSubject().String()
+
+	c := &Credential{
+		Context:           []string{ContextV2},
+		Type:              withBaseType(verifiableCredential.CredentialType(), TypeVerifiableCredential),
+		Issuer:            verifiableCredential.Issuer().String(),
+		ValidFrom:         credential.DateTime(verifiableCredential.ValidFrom()),
+		CredentialSubject: claims,
+	}
+
+	// the native encoding is used for fields that are not otherwise accessible
+	encodedCredential, err := verifiableCredential.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	var native struct {
+		ID         string `json:"id"`
+		ValidUntil string `json:"validUntil"`
+	}
+
+	err = json.Unmarshal(encodedCredential, &native)
+	if err != nil {
+		return nil, err
+	}
+
+	c.ID = native.ID
+	c.ValidUntil = native.ValidUntil
+
+	return c, nil
+}
+
+// ExportCredential converts a verifiable credential to a W3C credential, secured with a
+// data integrity proof signed by the credential's signer. The signer must be held by the account
+func ExportCredential(signer Signer, verifiableCredential *credential.VerifiableCredential) ([]byte, error) {
+	c, err := FromCredential(verifiableCredential)
+	if err != nil {
+		return nil, err
+	}
+
+	verificationMethod, err := verifiableCredential.Signer()
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Sign(signer, verificationMethod, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Encode()
+}
+
+// Issuer issues native credentials with keys held by an account, such as Account
+type Issuer interface {
+	Signer
+	CredentialIssue(unverifiedCredential *credential.Credential) (*credential.VerifiableCredential, error)
+}
+
+// ImportCredential verifies a W3C credential's proof, then converts it to a native verifiable
+// credential, reissued with the key that signed the proof. The key must be held by the account,
+// so only credentials issued by the account's identities can be imported
+func ImportCredential(issuer Issuer, resolve Resolver, encodedCredential []byte) (*credential.VerifiableCredential, error) {
+	c, err := DecodeCredential(encodedCredential)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Verify(resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	nativeCredential, err := c.ToCredential()
+	if err != nil {
+		return nil, err
+	}
+
+	return issuer.CredentialIssue(nativeCredential)
+}
+
+// ToCredential converts the W3C credential to a native credential, signed with the verification
+// method of it's proof. The returned credential must be issued with Account.CredentialIssue
+func (c *Credential) ToCredential() (*credential.Credential, error) {
+	if c.Proof == nil {
+		return nil, fmt.Errorf("%w: proof missing", ErrInvalidProof)
+	}
+
+	verificationMethod, err := credential.DecodeAddress(c.Proof.VerificationMethod)
+	if err != nil || verificationMethod.SigningKey() == nil {
+		return nil, fmt.Errorf("%w: invalid verification method", ErrInvalidProof)
+	}
+
+	issuer, err := credential.DecodeAddress(c.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectID, ok := c.CredentialSubject["id"].(string)
+	if !ok {
+		return nil, errors.New("credential subject does not have an id")
+	}
+
+	subject, err := credential.DecodeAddress(subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]interface{}, len(c.CredentialSubject))
+
+	for key, value := range c.CredentialSubject {
+		if key != "id" {
+			claims[key] = value
+		}
+	}
+
+	var credentialType []string
+
+	for _, t := range c.Type {
+		if t != TypeVerifiableCredential {
+			credentialType = append(credentialType, t)
+		}
+	}
+
+	issuedAt, err := time.Parse(time.RFC3339, c.Proof.Created)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid created timestamp", ErrInvalidProof)
+	}
+
+	builder := credential.NewCredential().
+		CredentialType(credentialType...).
+		CredentialSubject(subject).
+		CredentialSubjectClaims(claims).
+		Issuer(issuer)
+
+	if c.ValidFrom != "" {
+		validFrom, err := time.Parse(time.RFC3339, c.ValidFrom)
+		if err != nil {
+			return nil, fmt.Errorf("credential has an invalid validFrom: %w", err)
+		}
+
+		builder.ValidFrom(validFrom)
+	}
+
+	if c.ValidUntil != "" {
+		validUntil, err := time.Parse(time.RFC3339, c.ValidUntil)
+		if err != nil {
+			return nil, fmt.Errorf("credential has an invalid validUntil: %w", err)
+		}
+
+		builder.ValidUntil(validUntil)
+	}
+
+	return builder.
+		SignWith(verificationMethod.SigningKey(), issuedAt).
+		Finish()
+}
+
+// DecodeCredential decodes a json-ld encoded W3C credential
+func DecodeCredential(encodedCredential []byte) (*Credential, error) {
+	var c Credential
+
+	err := json.Unmarshal(encodedCredential, &c)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.Context) < 1 || c.Context[0] != ContextV2 {
+		return nil, errors.New("credential does not use the verifiable credentials 2.0 context")
+	}
+
+	return &c, nil
+}
+
+// Encode encodes the credential as json-ld
+func (c *Credential) Encode() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+// Sign secures the credential with a data integrity proof. The verification
+// method must be an address of the issuer that specifies a signing key
+func (c *Credential) Sign(signer Signer, verificationMethod *credential.Address, created time.Time) error {
+	proof, err := createProof(signer, c.unsecured(), c.Context, verificationMethod, &Proof{
+		Created:      credential.DateTime(created),
+		ProofPurpose: ProofPurposeAssertion,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	c.Proof = proof
+
+	return nil
+}
+
+// Verify verifies the credential's data integrity proof, and that it was signed with
+// a key that currently has the assertion role for the issuer's identity. A resolver is
+// required for credentials issued by aure addresses
+func (c *Credential) Verify(resolve Resolver) error {
+	issuer, err := credential.DecodeAddress(c.Issuer)
+	if err != nil {
+		return err
+	}
+
+	return verifyProof(c.unsecured(), c.Context, c.Proof, ProofPurposeAssertion, issuer, resolve, identity.RoleAssertion)
+}
+
+// ValidAt returns true if the credential is within it's validity period
+func (c *Credential) ValidAt(at time.Time) bool {
+	if c.ValidFrom != "" {
+		validFrom, err := time.Parse(time.RFC3339, c.ValidFrom)
+		if err != nil || at.Before(validFrom) {
+			return false
+		}
+	}
+
+	if c.ValidUntil != "" {
+		validUntil, err := time.Parse(time.RFC3339, c.ValidUntil)
+		if err != nil || at.After(validUntil) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Credential) unsecured() *Credential {
+	unsecured := *c
+	unsecured.Proof = nil
+	return &unsecured
+}
+
+// NewPresentation creates an unsecured presentation of W3C credentials by a holder
+func NewPresentation(holder *credential.Address, presentationType []string, credentials ...*Credential) *Presentation {
+	return &Presentation{
+		Context:              []string{ContextV2},
+		Type:                 withBaseType(presentationType, TypeVerifiablePresentation),
+		Holder:               holder.String(),
+		VerifiableCredential: credentials,
+	}
+}
+
+// DecodePresentation decodes a json-ld encoded W3C presentation
+func DecodePresentation(encodedPresentation []byte) (*Presentation, error) {
+	var p Presentation
+
+	err := json.Unmarshal(encodedPresentation, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.Context) < 1 || p.Context[0] != ContextV2 {
+		return nil, errors.New("presentation does not use the verifiable credentials 2.0 context")
+	}
+
+	return &p, nil
+}
+
+// Encode encodes the presentation as json-ld
+func (p *Presentation) Encode() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// Sign secures the presentation with a data integrity proof. The verification method must be
+// an address of the holder that specifies a signing key. The challenge and domain provided by
+// the verifier are included in the proof to prevent replay
+func (p *Presentation) Sign(signer Signer, verificationMethod *credential.Address, created time.Time, challenge, domain string) error {
+	proof, err := createProof(signer, p.unsecured(), p.Context, verificationMethod, &Proof{
+		Created:      credential.DateTime(created),
+		ProofPurpose: ProofPurposeAuthentication,
+		Challenge:    challenge,
+		Domain:       domain,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	p.Proof = proof
+
+	return nil
+}
+
+// Verify verifies the presentation's data integrity proof and challenge, and the proofs of each
+// of it's credentials. Signing keys must currently have the required roles for their identity. A resolver is required for holders and issuers with aure addresses
+func (p *Presentation) Verify(resolve Resolver, challenge, domain string) error {
+	holder, err := credential.DecodeAddress(p.Holder)
+	if err != nil {
+		return err
+	}
+
+	if p.Proof != nil && (p.Proof.Challenge != challenge || p.Proof.Domain != domain) {
+		return fmt.Errorf("%w: challenge or domain does not match", ErrInvalidProof)
+	}
+
+	err = verifyProof(p.unsecured(), p.Context, p.Proof, ProofPurposeAuthentication, holder, resolve, identity.RoleAuthentication, identity.RoleAssertion)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range p.VerifiableCredential {
+		err = c.Verify(resolve)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Presentation) unsecured() *Presentation {
+	unsecured := *p
+	unsecured.Proof = nil
+	return &unsecured
+}
+
+func withBaseType(types []string, baseType string) []string {
+	for _, t := range types {
+		if t == baseType {
+			return types
+		}
+	}
+
+	return append([]string{baseType}, types...)
+}
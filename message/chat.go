@@ -9,6 +9,8 @@ package message
 */
 import "C"
 import (
+	"bytes"
+	"errors"
 	"runtime"
 	"unsafe"
 
@@ -24,6 +26,9 @@ func newSigningPublicKey(*C.self_signing_public_key) *signing.PublicKey
 //go:linkname newPlatformAttestation github.com/joinself/self-go-sdk/platform.newPlatformAttestation
 func newPlatformAttestation(*C.self_platform_attestation) *platform.Attestation
 
+// ErrThumbnailMismatch returned when attaching a thumbnail that was not generated from the attachment
+var ErrThumbnailMismatch = errors.New("thumbnail was not generated from attachment")
+
 type Chat struct {
 	ptr *C.self_message_content_chat
 }
@@ -44,6 +49,7 @@ func newChat(ptr *C.self_message_content_chat) *Chat {
 
 type ChatBuilder struct {
 	ptr *C.self_message_content_chat_builder
+	err error
 }
 
 func newChatBuilder(ptr *C.self_message_content_chat_builder) *ChatBuilder {
@@ -113,6 +119,23 @@ func (c *Chat) Attachments() []*object.Object {
 	return attachments
 }
 
+// Thumbnail returns the thumbnail attached along with an attachment
+func (c *Chat) Thumbnail(attachment *object.Object) (*object.Object, bool) {
+	return thumbnailOf(c.Attachments(), attachment.Hash())
+}
+
+// thumbnailOf finds the thumbnail of the object with the given hash
+func thumbnailOf(attachments []*object.Object, hash []byte) (*object.Object, bool) {
+	for _, attachment := range attachments {
+		original, ok := attachment.ThumbnailOf()
+		if ok && bytes.Equal(original, hash) {
+			return attachment, true
+		}
+	}
+
+	return nil, false
+}
+
 // NewChat constructs a new chat message
 func NewChat() *ChatBuilder {
 	return newChatBuilder(C.self_message_content_chat_builder_init())
@@ -156,8 +179,27 @@ func (b *ChatBuilder) Attach(attachment *object.Object) *ChatBuilder {
 	return b
 }
 
+// AttachWithThumbnail attaches an object to the message, along with a thumbnail
+// of the object that can be used as a preview. The thumbnail must have been generated
+// from the attachment with Object.Thumbnail, which links it to the attachment so the
+// recipient can find it with Chat.Thumbnail. Both objects must be uploaded
+// before the message is sent
+func (b *ChatBuilder) AttachWithThumbnail(attachment, thumbnail *object.Object) *ChatBuilder {
+	original, ok := thumbnail.ThumbnailOf()
+	if !ok || !bytes.Equal(original, attachment.Hash()) {
+		b.err = ErrThumbnailMismatch
+		return b
+	}
+
+	return b.Attach(attachment).Attach(thumbnail)
+}
+
 // Finish finalizes the chat message and prepares it for sending
 func (b *ChatBuilder) Finish() (*Content, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
 	var finishedContent *C.self_message_content
 
 	result := C.self_message_content_chat_builder_finish(
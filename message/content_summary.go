@@ -13,6 +13,7 @@ import (
 	"unsafe"
 
 	"github.com/joinself/self-go-sdk/object"
+	"github.com/joinself/self-go-sdk/status"
 )
 
 type ContentSummaryDescriptionType int
@@ -102,6 +103,16 @@ func (c *ContentSummary) ContentType() ContentType {
 	)
 }
 
+// Title returns the title of the notification, or an empty string if not set
+func (c *ContentSummary) Title() string {
+	return C.GoString(C.self_message_content_summary_title(c.ptr))
+}
+
+// Body returns the body of the notification, or an empty string if not set
+func (c *ContentSummary) Body() string {
+	return C.GoString(C.self_message_content_summary_body(c.ptr))
+}
+
 // Descriptions get the descriptions
 func (c *ContentSummary) Descriptions() []*ContentSummaryDescription {
 	collection := C.self_message_content_summary_descriptions(c.ptr)
@@ -119,6 +130,102 @@ func (c *ContentSummary) Descriptions() []*ContentSummaryDescription {
 	return descriptions
 }
 
+// Preview returns the thumbnail of the first chat attachment that was attached
+// with a thumbnail, which can be displayed as a preview of the message
+func (c *ContentSummary) Preview() (*object.Object, bool) {
+	var attachments []*object.Object
+
+	for _, description := range c.Descriptions() {
+		attachment, ok := description.ChatAttachment()
+		if ok {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	for _, attachment := range attachments {
+		if _, ok := attachment.ThumbnailOf(); ok {
+			continue
+		}
+
+		thumbnail, ok := thumbnailOf(attachments, attachment.Hash())
+		if ok {
+			return thumbnail, true
+		}
+	}
+
+	return nil, false
+}
+
+type ContentSummaryBuilder struct {
+	ptr *C.self_message_content_summary_builder
+}
+
+func newContentSummaryBuilder(ptr *C.self_message_content_summary_builder) *ContentSummaryBuilder {
+	c := &ContentSummaryBuilder{
+		ptr: ptr,
+	}
+
+	runtime.AddCleanup(c, func(ptr *C.self_message_content_summary_builder) {
+		C.self_message_content_summary_builder_destroy(
+			ptr,
+		)
+	}, c.ptr)
+
+	return c
+}
+
+// NewContentSummary constructs a new summary of the content, populated
+// with the contents default descriptions
+func NewContentSummary(content *Content) *ContentSummaryBuilder {
+	return newContentSummaryBuilder(C.self_message_content_summary_builder_init(
+		content.ptr,
+	))
+}
+
+// Title sets the title displayed by the notification
+func (b *ContentSummaryBuilder) Title(title string) *ContentSummaryBuilder {
+	cTitle := C.CString(title)
+
+	C.self_message_content_summary_builder_title(
+		b.ptr,
+		cTitle,
+	)
+
+	C.free(unsafe.Pointer(cTitle))
+
+	return b
+}
+
+// Body sets the body displayed by the notification
+func (b *ContentSummaryBuilder) Body(body string) *ContentSummaryBuilder {
+	cBody := C.CString(body)
+
+	C.self_message_content_summary_builder_body(
+		b.ptr,
+		cBody,
+	)
+
+	C.free(unsafe.Pointer(cBody))
+
+	return b
+}
+
+// Finish finalizes the summary and prepares it for sending
+func (b *ContentSummaryBuilder) Finish() (*ContentSummary, error) {
+	var finishedSummary *C.self_message_content_summary
+
+	result := C.self_message_content_summary_builder_finish(
+		b.ptr,
+		&finishedSummary,
+	)
+
+	if result > 0 {
+		return nil, status.New(result)
+	}
+
+	return newContentSummary(finishedSummary), nil
+}
+
 type ContentSummaryDescription struct {
 	ptr *C.self_message_content_summary_description
 }
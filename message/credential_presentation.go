@@ -95,7 +95,9 @@ func newCredentialPresentationResponse(ptr *C.self_message_content_credential_pr
 }
 
 type CredentialPresentationRequestBuilder struct {
-	ptr *C.self_message_content_credential_presentation_request_builder
+	ptr        *C.self_message_content_credential_presentation_request_builder
+	predicates *predicate.Tree
+	clock      func() time.Time
 }
 
 func newCredentialPresentationRequestBuilder(ptr *C.self_message_content_credential_presentation_request_builder) *CredentialPresentationRequestBuilder {
@@ -185,6 +187,10 @@ func (c *CredentialPresentationRequest) Holder() (*credential.Address, error) {
 		return nil, status.New(result)
 	}
 
+	if holder == nil {
+		return nil, nil
+	}
+
 	return newAddress(
 		holder,
 	), nil
@@ -192,20 +198,32 @@ func (c *CredentialPresentationRequest) Holder() (*credential.Address, error) {
 
 // BiometricAnchor returns the pairwise biometric anchor of the holder. returns nil if not specified
 func (c *CredentialPresentationRequest) BiometricAnchor() []byte {
+	biometricAnchor := C.self_message_content_credential_presentation_request_biometric_anchor(
+		c.ptr,
+	)
+
+	if biometricAnchor == nil {
+		return nil
+	}
+
 	return C.GoBytes(
-		unsafe.Pointer(C.self_message_content_credential_presentation_request_biometric_anchor(
-			c.ptr,
-		)),
+		unsafe.Pointer(biometricAnchor),
 		32,
 	)
 }
 
 // Challenge returns the 32 byte challenge generated by the requester. returns nil if not specified
 func (c *CredentialPresentationRequest) Challenge() []byte {
+	challenge := C.self_message_content_credential_presentation_request_challenge(
+		c.ptr,
+	)
+
+	if challenge == nil {
+		return nil
+	}
+
 	return C.GoBytes(
-		unsafe.Pointer(C.self_message_content_credential_presentation_request_challenge(
-			c.ptr,
-		)),
+		unsafe.Pointer(challenge),
 		32,
 	)
 }
@@ -330,13 +348,16 @@ func (b *CredentialPresentationRequestBuilder) Term(term *credential.Term) *Cred
 	return b
 }
 
-// Predicates specifies the predicates that returned credentials must match
+// Predicates specifies the predicates that returned credentials must match. Predicates relative
+// to the current time, such as predicate.AgeAtLeast, are resolved when the request is finished
 func (b *CredentialPresentationRequestBuilder) Predicates(tree *predicate.Tree) *CredentialPresentationRequestBuilder {
-	C.self_message_content_credential_presentation_request_builder_predicates(
-		b.ptr,
-		credentialPredicateTreePtr(tree),
-	)
+	b.predicates = tree
+	return b
+}
 
+// Clock sets the clock that relative predicates are resolved against. Defaults to time.Now
+func (b *CredentialPresentationRequestBuilder) Clock(clock func() time.Time) *CredentialPresentationRequestBuilder {
+	b.clock = clock
 	return b
 }
 
@@ -364,6 +385,18 @@ func (b *CredentialPresentationRequestBuilder) Expires(expires time.Time) *Crede
 func (b *CredentialPresentationRequestBuilder) Finish() (*Content, error) {
 	var finishedContent *C.self_message_content
 
+	if b.predicates != nil {
+		now := time.Now()
+		if b.clock != nil {
+			now = b.clock()
+		}
+
+		C.self_message_content_credential_presentation_request_builder_predicates(
+			b.ptr,
+			credentialPredicateTreePtr(b.predicates.ResolveAt(now)),
+		)
+	}
+
 	result := C.self_message_content_credential_presentation_request_builder_finish(
 		b.ptr,
 		&finishedContent,
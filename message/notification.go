@@ -0,0 +1,221 @@
+package message
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+)
+
+// DefaultLocale the locale used when no template exists for the requested locale
+const DefaultLocale = "en"
+
+// NotificationData the values available to notification templates
+type NotificationData struct {
+	// Sender the display name of the sender
+	Sender string
+	// ContentType the type of the content being summarised
+	ContentType ContentType
+	// Message the message of a chat
+	Message string
+	// Attachments the number of objects attached to a chat
+	Attachments int
+	// Credentials the names of the credentials being issued or requested
+	Credentials []string
+	// Presentations the names of the presentations being requested
+	Presentations []string
+}
+
+type notificationTemplate struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// NotificationComposer generates the title and body of push notifications from
+// templates keyed by locale and content type
+type NotificationComposer struct {
+	templates map[string]map[ContentType]*notificationTemplate
+	mu        sync.RWMutex
+}
+
+// NewNotificationComposer creates a new notification composer populated with default english templates
+func NewNotificationComposer() *NotificationComposer {
+	c := &NotificationComposer{
+		templates: make(map[string]map[ContentType]*notificationTemplate),
+	}
+
+	defaults := []struct {
+		contentType ContentType
+		title       string
+		body        string
+	}{
+		{ContentTypeChat, "{{.Sender}}", "{{if .Message}}{{.Message}}{{else if .Attachments}}Sent {{.Attachments}} attachment{{if gt .Attachments 1}}s{{end}}{{end}}"},
+		{ContentTypeCredential, "{{.Sender}}", "{{.Sender}} issued you {{join .Credentials}}"},
+		{ContentTypeCredentialPresentationRequest, "{{.Sender}}", "{{.Sender}} requests your {{join .Presentations}}"},
+		{ContentTypeCredentialVerificationRequest, "{{.Sender}}", "{{.Sender}} requests verification of your {{join .Credentials}}"},
+		{ContentTypeSigningRequest, "{{.Sender}}", "{{.Sender}} requests your signature"},
+		{ContentTypeAccountPairingRequest, "{{.Sender}}", "{{.Sender}} requests to pair with your account"},
+		{ContentTypeIntroduction, "{{.Sender}}", "{{.Sender}} introduced themselves"},
+	}
+
+	for _, d := range defaults {
+		// default templates are known to be valid
+		_ = c.Template(DefaultLocale, d.contentType, d.title, d.body)
+	}
+
+	return c
+}
+
+// Template sets the title and body templates used for a content type in the given locale.
+// Templates use the text/template syntax, are executed with NotificationData, and can use
+// the join function to join a list of names, such as {{join .Presentations}}
+func (c *NotificationComposer) Template(locale string, contentType ContentType, title, body string) error {
+	titleTemplate, err := template.New("title").Funcs(notificationFuncs).Parse(title)
+	if err != nil {
+		return err
+	}
+
+	bodyTemplate, err := template.New("body").Funcs(notificationFuncs).Parse(body)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	locale = strings.ToLower(locale)
+
+	if c.templates[locale] == nil {
+		c.templates[locale] = make(map[ContentType]*notificationTemplate)
+	}
+
+	c.templates[locale][contentType] = &notificationTemplate{
+		title: titleTemplate,
+		body:  bodyTemplate,
+	}
+
+	return nil
+}
+
+// Compose creates a summary of the content for sending as a push notification, with a title and body
+// generated from the template for the content's type. If there is no template for the locale, the
+// template for the locale's base language, such as "en" for "en-GB", or the default locale is used.
+// If there is no template for the content type, the summary will only contain it's default descriptions
+func (c *NotificationComposer) Compose(content *Content, locale, sender string) (*ContentSummary, error) {
+	summary, err := content.Summary()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := c.lookup(locale, content.ContentType())
+	if tmpl == nil {
+		return summary, nil
+	}
+
+	data := NotificationData{
+		Sender:      sender,
+		ContentType: content.ContentType(),
+	}
+
+	for _, description := range summary.Descriptions() {
+		switch description.DescriptionType() {
+		case ContentSummaryDescriptionTypeChatMessage:
+			data.Message, _ = description.ChatMessage()
+		case ContentSummaryDescriptionTypeChatAttachment:
+			data.Attachments++
+		case ContentSummaryDescriptionTypeCredential:
+			credentials, _ := description.Credential()
+			data.Credentials = append(data.Credentials, displayNames(credentials, "Credential")...)
+		case ContentSummaryDescriptionTypePresentation:
+			presentations, _ := description.Presentation()
+			data.Presentations = append(data.Presentations, displayNames(presentations, "Presentation")...)
+		}
+	}
+
+	var title, body bytes.Buffer
+
+	err = tmpl.title.Execute(&title, &data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute notification title template: %w", err)
+	}
+
+	err = tmpl.body.Execute(&body, &data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute notification body template: %w", err)
+	}
+
+	return NewContentSummary(content).
+		Title(title.String()).
+		Body(body.String()).
+		Finish()
+}
+
+func (c *NotificationComposer) lookup(locale string, contentType ContentType) *notificationTemplate {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	locale = strings.ToLower(locale)
+
+	candidates := []string{locale}
+
+	if language, _, found := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-"); found {
+		candidates = append(candidates, language)
+	}
+
+	candidates = append(candidates, DefaultLocale)
+
+	for _, candidate := range candidates {
+		tmpl, ok := c.templates[candidate][contentType]
+		if ok {
+			return tmpl
+		}
+	}
+
+	return nil
+}
+
+var notificationFuncs = template.FuncMap{
+	"join": joinNames,
+}
+
+// joinNames joins a list of names, such as "Passport, Email and Phone"
+func joinNames(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	default:
+		return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+	}
+}
+
+// displayNames converts credential and presentation types to human readable
+// names, such as "LivenessAndFacialComparisonPresentation" to "Liveness And Facial Comparison"
+func displayNames(types []string, suffix string) []string {
+	names := make([]string, 0, len(types))
+
+	for _, t := range types {
+		if t == "Verifiable"+suffix {
+			continue
+		}
+
+		var name strings.Builder
+
+		for i, r := range strings.TrimSuffix(t, suffix) {
+			if i > 0 && unicode.IsUpper(r) {
+				name.WriteRune(' ')
+			}
+
+			name.WriteRune(r)
+		}
+
+		if name.Len() > 0 {
+			names = append(names, name.String())
+		}
+	}
+
+	return names
+}
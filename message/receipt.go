@@ -0,0 +1,189 @@
+package message
+
+/*
+#cgo LDFLAGS: -lstdc++ -lm -ldl
+#cgo darwin LDFLAGS: -lself_sdk -framework CoreFoundation -framework SystemConfiguration -framework Security
+#cgo linux LDFLAGS: -lself_sdk
+#include <self-sdk.h>
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"runtime"
+	"unsafe"
+
+	"github.com/joinself/self-go-sdk/status"
+)
+
+const (
+	DeliveryStatusUnknown DeliveryStatus = iota
+	DeliveryStatusSent
+	DeliveryStatusRelayed
+	DeliveryStatusDelivered
+	DeliveryStatusRead
+)
+
+// DeliveryStatus the delivery state of a sent message
+type DeliveryStatus int
+
+func (s DeliveryStatus) String() string {
+	switch s {
+	case DeliveryStatusSent:
+		return "Sent"
+	case DeliveryStatusRelayed:
+		return "Relayed"
+	case DeliveryStatusDelivered:
+		return "Delivered"
+	case DeliveryStatusRead:
+		return "Read"
+	default:
+		return "Unknown"
+	}
+}
+
+type Receipt struct {
+	ptr *C.self_message_content_receipt
+}
+
+func newReceipt(ptr *C.self_message_content_receipt) *Receipt {
+	c := &Receipt{
+		ptr: ptr,
+	}
+
+	runtime.AddCleanup(c, func(ptr *C.self_message_content_receipt) {
+		C.self_message_content_receipt_destroy(
+			ptr,
+		)
+	}, c.ptr)
+
+	return c
+}
+
+type ReceiptBuilder struct {
+	ptr *C.self_message_content_receipt_builder
+}
+
+func newReceiptBuilder(ptr *C.self_message_content_receipt_builder) *ReceiptBuilder {
+	c := &ReceiptBuilder{
+		ptr: ptr,
+	}
+
+	runtime.AddCleanup(c, func(ptr *C.self_message_content_receipt_builder) {
+		C.self_message_content_receipt_builder_destroy(
+			ptr,
+		)
+	}, c.ptr)
+
+	return c
+}
+
+// DecodeReceipt decodes a message to a receipt
+func DecodeReceipt(content *Content) (*Receipt, error) {
+	contentPtr := contentPtr(content)
+
+	var receiptContent *C.self_message_content_receipt
+
+	result := C.self_message_content_as_receipt(
+		contentPtr,
+		&receiptContent,
+	)
+
+	if result > 0 {
+		return nil, status.New(result)
+	}
+
+	return newReceipt(receiptContent), nil
+}
+
+// Delivered returns the ids of messages that have been delivered to the recipient's device
+func (c *Receipt) Delivered() [][]byte {
+	deliveredLen := int(C.self_message_content_receipt_delivered_len(c.ptr))
+
+	delivered := make([][]byte, deliveredLen)
+
+	for i := 0; i < deliveredLen; i++ {
+		delivered[i] = C.GoBytes(
+			unsafe.Pointer(C.self_message_content_receipt_delivered_at(c.ptr, C.size_t(i))),
+			20,
+		)
+	}
+
+	return delivered
+}
+
+// Read returns the ids of messages that have been read by the recipient
+func (c *Receipt) Read() [][]byte {
+	readLen := int(C.self_message_content_receipt_read_len(c.ptr))
+
+	read := make([][]byte, readLen)
+
+	for i := 0; i < readLen; i++ {
+		read[i] = C.GoBytes(
+			unsafe.Pointer(C.self_message_content_receipt_read_at(c.ptr, C.size_t(i))),
+			20,
+		)
+	}
+
+	return read
+}
+
+// NewReceipt constructs a new receipt message
+func NewReceipt() *ReceiptBuilder {
+	return newReceiptBuilder(C.self_message_content_receipt_builder_init())
+}
+
+// Delivered marks messages as having been delivered
+func (b *ReceiptBuilder) Delivered(messageIDs ...[]byte) *ReceiptBuilder {
+	for i := range messageIDs {
+		if len(messageIDs[i]) != 20 {
+			continue
+		}
+
+		idBuf := C.CBytes(messageIDs[i])
+
+		C.self_message_content_receipt_builder_delivered(
+			b.ptr,
+			(*C.uint8_t)(idBuf),
+		)
+
+		C.free(idBuf)
+	}
+
+	return b
+}
+
+// Read marks messages as having been read
+func (b *ReceiptBuilder) Read(messageIDs ...[]byte) *ReceiptBuilder {
+	for i := range messageIDs {
+		if len(messageIDs[i]) != 20 {
+			continue
+		}
+
+		idBuf := C.CBytes(messageIDs[i])
+
+		C.self_message_content_receipt_builder_read(
+			b.ptr,
+			(*C.uint8_t)(idBuf),
+		)
+
+		C.free(idBuf)
+	}
+
+	return b
+}
+
+// Finish finalizes the receipt and prepares it for sending
+func (b *ReceiptBuilder) Finish() (*Content, error) {
+	var finishedContent *C.self_message_content
+
+	result := C.self_message_content_receipt_builder_finish(
+		b.ptr,
+		&finishedContent,
+	)
+
+	if result > 0 {
+		return nil, status.New(result)
+	}
+
+	return newContent(finishedContent), nil
+}
@@ -0,0 +1,256 @@
+// Package requests provides reusable credential presentation request templates for common
+// verification scenarios, along with validators that check the presentations returned in
+// response and extract the holder's typed claims
+package requests
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/credential/predicate"
+	"github.com/joinself/self-go-sdk/message"
+)
+
+const (
+	// TemplateEmail requests a verified email address. Accepts an optional domain parameter
+	// that the email address must belong to
+	TemplateEmail = "email"
+	// TemplatePhone requests a verified phone number
+	TemplatePhone = "phone"
+	// TemplateKYC requests a passport, along with a liveness and facial comparison check linking the holder to it
+	TemplateKYC = "kyc"
+	// TemplateOverAge requests proof the holder is over an age from their passport. Accepts an
+	// optional age parameter, which defaults to 18
+	TemplateOverAge = "over-age"
+)
+
+// ParamExpiry the parameter accepted by all built-in templates that sets how long requests are valid for, such as 10m
+const ParamExpiry = "expiry"
+
+// DefaultExpiry the period requests created from built-in templates are valid for
+const DefaultExpiry = time.Minute * 10
+
+var (
+	// ErrTemplateNotFound returned when creating a template that has not been registered
+	ErrTemplateNotFound = errors.New("request template not found")
+	// ErrInvalidParameter returned when a template is created with an invalid parameter
+	ErrInvalidParameter = errors.New("request template parameter invalid")
+
+	templates = map[string]Factory{
+		TemplateEmail:   emailTemplate,
+		TemplatePhone:   phoneTemplate,
+		TemplateKYC:     kycTemplate,
+		TemplateOverAge: overAgeTemplate,
+	}
+	templatesMu sync.RWMutex
+)
+
+// Params the parameters a template is created with
+type Params map[string]string
+
+// Factory creates a template from it's parameters
+type Factory func(params Params) (*Template, error)
+
+// Template a named credential presentation request
+type Template struct {
+	// Name the name the template was registered with
+	Name string
+	// PresentationType the type of presentation requested
+	PresentationType []string
+	// Predicates the predicates the presented credentials must match. Predicates relative to
+	// the current time are resolved when the request is created, and again when it is validated
+	Predicates *predicate.Tree
+	// Requirements further predicates that must each be matched when the response is validated, by the
+	// same or a different credential to Predicates, such as a liveness check presented alongside a passport
+	Requirements []*predicate.Tree
+	// Term the term the credentials are requested under. nil requests credentials without a term
+	Term *credential.Term
+	// Expiry the period requests are valid for. zero creates requests that do not expire
+	Expiry time.Duration
+	// ValidDocument requires the holder to have a valid document that is linked to their biometric anchor
+	ValidDocument bool
+	// Check checks the claims of a validated result, for requirements that cannot be expressed
+	// as predicates. Returning an error fails validation. nil performs no further checks
+	Check func(result *Result) error
+}
+
+// Register registers a template factory, replacing any existing template with the same name
+func Register(name string, factory Factory) {
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+
+	templates[name] = factory
+}
+
+// Templates returns the names of all registered templates
+func Templates() []string {
+	templatesMu.RLock()
+	defer templatesMu.RUnlock()
+
+	names := make([]string, 0, len(templates))
+
+	for name := range templates {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// New creates a template from the factory registered with the given name
+func New(name string, params Params) (*Template, error) {
+	templatesMu.RLock()
+	factory, ok := templates[name]
+	templatesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	if params == nil {
+		params = Params{}
+	}
+
+	template, err := factory(params)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Name = name
+
+	return template, nil
+}
+
+// Request creates a credential presentation request from the template. The returned
+// builder can be used to set further options, such as the holder, before it is finished
+func (t *Template) Request() *message.CredentialPresentationRequestBuilder {
+	b := message.NewCredentialPresentationRequest().
+		PresentationType(t.PresentationType...)
+
+	if t.Predicates != nil {
+		b.Predicates(t.Predicates)
+	}
+
+	if t.Term != nil {
+		b.Term(t.Term)
+	}
+
+	if t.Expiry > 0 {
+		b.Expires(time.Now().Add(t.Expiry))
+	}
+
+	return b
+}
+
+func emailTemplate(params Params) (*Template, error) {
+	p := predicate.Contains(credential.FieldType, credential.CredentialTypeEmail).
+		And(predicate.NotEmpty(credential.FieldSubjectEmailAddress))
+
+	domain, ok := params["domain"]
+	if ok {
+		if domain == "" {
+			return nil, fmt.Errorf("%w: domain must not be empty", ErrInvalidParameter)
+		}
+
+		p = p.And(predicate.Contains(credential.FieldSubjectEmailAddress, "@"+domain))
+	}
+
+	template, err := newTemplate(params, []string{credential.PresentationTypeContactDetails}, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		// predicates can only check the address contains the domain,
+		// which also matches addresses such as alice@example.com.evil.org
+		template.Check = func(result *Result) error {
+			if result.Email == nil {
+				return fmt.Errorf("%w: email address not presented", ErrUnsatisfied)
+			}
+
+			at := strings.LastIndexByte(result.Email.EmailAddress, '@')
+
+			if at < 0 || !strings.EqualFold(result.Email.EmailAddress[at+1:], domain) {
+				return fmt.Errorf("%w: email address must belong to %s", ErrUnsatisfied, domain)
+			}
+
+			return nil
+		}
+	}
+
+	return template, nil
+}
+
+func phoneTemplate(params Params) (*Template, error) {
+	p := predicate.Contains(credential.FieldType, credential.CredentialTypePhone).
+		And(predicate.NotEmpty(credential.FieldSubjectPhoneNumber))
+
+	return newTemplate(params, []string{credential.PresentationTypeContactDetails}, p)
+}
+
+func kycTemplate(params Params) (*Template, error) {
+	p := predicate.Contains(credential.FieldType, credential.CredentialTypePassport).
+		And(predicate.NotEmpty(credential.FieldSubjectPassportDocumentNumber))
+
+	template, err := newTemplate(params, []string{
+		credential.PresentationTypePassport,
+		credential.PresentationTypeLivenessAndFacialComparison,
+	}, p)
+	if err != nil {
+		return nil, err
+	}
+
+	// the liveness check is a separate credential to the passport, so cannot be chained with it's predicates
+	template.Requirements = []*predicate.Tree{
+		predicate.NewTree(predicate.Contains(credential.FieldType, credential.CredentialTypeLivenessAndFacialComparison)),
+	}
+	template.ValidDocument = true
+
+	return template, nil
+}
+
+func overAgeTemplate(params Params) (*Template, error) {
+	age := 18
+
+	value, ok := params["age"]
+	if ok {
+		var err error
+
+		age, err = strconv.Atoi(value)
+		if err != nil || age < 0 {
+			return nil, fmt.Errorf("%w: age must be a number of years", ErrInvalidParameter)
+		}
+	}
+
+	p := predicate.Contains(credential.FieldType, credential.CredentialTypePassport).
+		And(predicate.AgeAtLeast(credential.FieldSubjectPassportDateOfBirth, age))
+
+	return newTemplate(params, []string{credential.PresentationTypePassport}, p)
+}
+
+func newTemplate(params Params, presentationType []string, p *predicate.Predicate) (*Template, error) {
+	template := &Template{
+		PresentationType: presentationType,
+		Predicates:       predicate.NewTree(p),
+		Expiry:           DefaultExpiry,
+	}
+
+	value, ok := params[ParamExpiry]
+	if ok {
+		expiry, err := time.ParseDuration(value)
+		if err != nil || expiry < 0 {
+			return nil, fmt.Errorf("%w: %s must be a duration", ErrInvalidParameter, ParamExpiry)
+		}
+
+		template.Expiry = expiry
+	}
+
+	return template, nil
+}
@@ -0,0 +1,260 @@
+package requests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/joinself/self-go-sdk/account"
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/credential/predicate"
+	"github.com/joinself/self-go-sdk/keypair/signing"
+	"github.com/joinself/self-go-sdk/message"
+)
+
+var (
+	// ErrResponseStatus returned when validating a response that was not successful
+	ErrResponseStatus = errors.New("presentation response was not successful")
+	// ErrPresentationType returned when a response does not contain a presentation of the requested type
+	ErrPresentationType = errors.New("response does not contain a presentation of the requested type")
+	// ErrUnexpectedResponse returned when a response does not answer the given request, or the request was not created from the template
+	ErrUnexpectedResponse = errors.New("response does not answer a request created from the template")
+	// ErrRequestExpired returned when a response is validated after the request it answers has expired
+	ErrRequestExpired = errors.New("presentation request has expired")
+	// ErrSenderMismatch returned when a response was not sent by the holder of it's presentations
+	ErrSenderMismatch = errors.New("response was not sent by the holder of the presentations")
+	// ErrHolderMismatch returned when a response contains presentations from more than one holder, or from a holder that was not requested
+	ErrHolderMismatch = errors.New("presentations belong to more than one holder")
+	// ErrInvalidDocument returned when the holder does not have a valid document linked to their biometric anchor
+	ErrInvalidDocument = errors.New("holder does not have a valid document")
+	// ErrUnsatisfied returned when the holder's valid credentials do not satisfy the template's predicates
+	ErrUnsatisfied = errors.New("presented credentials do not satisfy the request")
+)
+
+// Result the verified credentials of a holder, along with the typed claims extracted from them.
+// Claims are only set if a credential of the claim's type matched the template's predicates
+type Result struct {
+	Template         string
+	Holder           *credential.Address
+	Credentials      []*credential.VerifiableCredential
+	Email            *credential.EmailClaims
+	Phone            *credential.PhoneClaims
+	Passport         *credential.PassportClaims
+	FacialComparison *credential.FacialComparisonClaims
+	BiometricAnchor  *credential.BiometricAnchorClaims
+	Organisation     *credential.OrganisationClaims
+	Application      *credential.ApplicationClaims
+}
+
+// Validator validates responses to requests created from a template
+type Validator struct {
+	template *Template
+	account  *account.Account
+	registry *credential.TrustedIssuerRegistry
+	clock    func() time.Time
+}
+
+// Validator creates a validator for responses to requests created from the template. Presentations
+// are validated through a credential graph created by the account from the trusted issuer registry
+func (t *Template) Validator(acc *account.Account, registry *credential.TrustedIssuerRegistry) *Validator {
+	return &Validator{
+		template: t,
+		account:  acc,
+		registry: registry,
+		clock:    time.Now,
+	}
+}
+
+// Clock sets the clock that relative predicates are resolved against. Defaults to time.Now
+func (v *Validator) Clock(clock func() time.Time) *Validator {
+	v.clock = clock
+	return v
+}
+
+// Validate validates the presentations of a credential presentation response, sent from the given
+// address. The response must answer the request, which must have been created from the template and
+// must not have expired, and must be sent by the holder of it's presentations. If the request specifies
+// a holder, the presentations must belong to it
+func (v *Validator) Validate(request *message.Content, from *signing.PublicKey, response *message.CredentialPresentationResponse) (*Result, error) {
+	if !bytes.Equal(response.ResponseTo(), request.ID()) {
+		return nil, ErrUnexpectedResponse
+	}
+
+	presentationRequest, err := message.DecodeCredentialPresentationRequest(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedResponse, err)
+	}
+
+	if !slices.Equal(presentationRequest.PresentationType(), v.template.PresentationType) {
+		return nil, ErrUnexpectedResponse
+	}
+
+	expires := presentationRequest.Expires()
+
+	if expires.Unix() > 0 && v.clock().After(expires) {
+		return nil, fmt.Errorf("%w: expired at %s", ErrRequestExpired, expires.Format(time.RFC3339))
+	}
+
+	status := response.Status()
+
+	if status != message.ResponseStatusOk {
+		return nil, fmt.Errorf("%w: %s", ErrResponseStatus, status)
+	}
+
+	result, err := v.ValidatePresentations(response.Presentations())
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey := result.Holder.SigningKey()
+
+	if !result.Holder.Address().Matches(from) && (signingKey == nil || !signingKey.Matches(from)) {
+		return nil, ErrSenderMismatch
+	}
+
+	holder, err := presentationRequest.Holder()
+	if err == nil && holder != nil && !holder.Address().Matches(result.Holder.Address()) {
+		return nil, ErrHolderMismatch
+	}
+
+	return result, nil
+}
+
+// ValidatePresentations validates presentations returned for a request created from the template.
+// The presentations must be held by a single holder, whose valid credentials must match the
+// template's predicates and each of it's requirements. The typed claims of the matching credentials are returned
+func (v *Validator) ValidatePresentations(presentations []*credential.VerifiablePresentation) (*Result, error) {
+	requested := slices.ContainsFunc(presentations, func(presentation *credential.VerifiablePresentation) bool {
+		return slices.ContainsFunc(presentation.PresentationType(), func(presentationType string) bool {
+			return slices.Contains(v.template.PresentationType, presentationType)
+		})
+	})
+
+	if !requested {
+		return nil, ErrPresentationType
+	}
+
+	holder := presentations[0].Holder()
+
+	for _, presentation := range presentations[1:] {
+		if presentation.Holder().String() != holder.String() {
+			return nil, ErrHolderMismatch
+		}
+	}
+
+	graph, err := v.account.CredentialGraphCreate(v.registry, presentations)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.template.ValidDocument && !graph.ValidDocumentFor(holder) {
+		return nil, ErrInvalidDocument
+	}
+
+	credentials, err := graph.ValidCredentialsFor(holder)
+	if err != nil {
+		return nil, err
+	}
+
+	trees := v.template.Requirements
+
+	if v.template.Predicates != nil {
+		trees = append([]*predicate.Tree{v.template.Predicates}, trees...)
+	}
+
+	var matched []*credential.VerifiableCredential
+
+	seen := make(map[string]bool)
+
+	for _, tree := range trees {
+		matches, ok := tree.ResolveAt(v.clock()).FindOptimalMatch(credentials)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnsatisfied, tree.String())
+		}
+
+		for _, match := range matches {
+			hash, err := match.CredentialHash()
+			if err != nil {
+				return nil, err
+			}
+
+			if seen[string(hash)] {
+				continue
+			}
+
+			seen[string(hash)] = true
+			matched = append(matched, match)
+		}
+	}
+
+	if len(trees) == 0 {
+		matched = credentials
+	}
+
+	result := &Result{
+		Template:    v.template.Name,
+		Holder:      holder,
+		Credentials: matched,
+	}
+
+	err = result.extract()
+	if err != nil {
+		return nil, err
+	}
+
+	if v.template.Check != nil {
+		err = v.template.Check(result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Unmarshal decodes the claims of the first matched credential of the given type into the struct
+// pointed to by v. See credential.UnmarshalClaims for how claims are mapped to the struct's fields
+func (r *Result) Unmarshal(credentialType string, v any) error {
+	for _, c := range r.Credentials {
+		if slices.Contains(c.CredentialType(), credentialType) {
+			return credential.UnmarshalClaims(c, v)
+		}
+	}
+
+	return fmt.Errorf("%w: %s", credential.ErrUnexpectedCredentialType, credentialType)
+}
+
+// extract decodes the typed claims of the built-in credential types
+func (r *Result) extract() error {
+	var err error
+
+	for _, c := range r.Credentials {
+		credentialType := c.CredentialType()
+
+		switch {
+		case slices.Contains(credentialType, credential.CredentialTypeEmail):
+			r.Email, err = credential.AsEmail(c)
+		case slices.Contains(credentialType, credential.CredentialTypePhone):
+			r.Phone, err = credential.AsPhone(c)
+		case slices.Contains(credentialType, credential.CredentialTypePassport):
+			r.Passport, err = credential.AsPassport(c)
+		case slices.Contains(credentialType, credential.CredentialTypeFacialComparison),
+			slices.Contains(credentialType, credential.CredentialTypeLivenessAndFacialComparison):
+			r.FacialComparison, err = credential.AsFacialComparison(c)
+		case slices.Contains(credentialType, credential.CredentialTypeBiometricAnchor):
+			r.BiometricAnchor, err = credential.AsBiometricAnchor(c)
+		case slices.Contains(credentialType, credential.CredentialTypeOrganisation):
+			r.Organisation, err = credential.AsOrganisation(c)
+		case slices.Contains(credentialType, credential.CredentialTypeApplication):
+			r.Application, err = credential.AsApplication(c)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
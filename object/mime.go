@@ -0,0 +1,121 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrMimeTypeNotAllowed returned when an objects content type is not permitted by a policy
+	ErrMimeTypeNotAllowed = errors.New("object mime type not allowed")
+	// ErrMimeTypeMismatch returned when an objects declared mime type does not match it's content
+	ErrMimeTypeMismatch = errors.New("object mime type does not match content")
+	// ErrObjectTooLarge returned when an objects size exceeds the maximum allowed by a policy
+	ErrObjectTooLarge = errors.New("object exceeds maximum size")
+
+	// PolicyImages permits the image formats that thumbnails can be generated for
+	PolicyImages = &Policy{
+		Allowed: []string{"image/jpeg", "image/png", "image/gif"},
+	}
+	// PolicyDocuments permits common image formats and pdf documents
+	PolicyDocuments = &Policy{
+		Allowed: []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf"},
+	}
+)
+
+// Policy restricts the type and size of objects
+type Policy struct {
+	// Allowed the mime types that are permitted. A type may use a wildcard subtype, such as "image/*"
+	Allowed []string
+	// MaxSize the maximum size of an object in bytes. zero permits any size
+	MaxSize int
+}
+
+// Allows returns true if the mime type is permitted by the policy
+func (p *Policy) Allows(mimeType string) bool {
+	mimeType = baseMimeType(mimeType)
+
+	for _, allowed := range p.Allowed {
+		if allowed == mimeType || allowed == "*/*" {
+			return true
+		}
+
+		if strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DetectMimeType detects the mime type of data from it's contents
+func DetectMimeType(data []byte) string {
+	return baseMimeType(http.DetectContentType(data))
+}
+
+// NewDetected creates a new object, using the mime type detected from the data.
+// Returns an error if the detected type or size is not permitted by the policy
+func NewDetected(data []byte, policy *Policy) (*Object, error) {
+	mimeType := DetectMimeType(data)
+
+	err := policy.check(mimeType, len(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return New(mimeType, data)
+}
+
+// Validate checks that the mime type of an objects contents matches it's declared
+// mime type, and that it is permitted by the policy. The object must have been downloaded
+func Validate(o *Object, policy *Policy) error {
+	data := o.Data()
+	declared := baseMimeType(o.MimeType())
+	detected := DetectMimeType(data)
+
+	// some formats, such as json, cannot be reliably detected, so are only
+	// required to match if the declared type is one that can be detected
+	if detected != declared && (detectable(declared) || detectable(detected)) {
+		return fmt.Errorf("%w: declared %s detected %s", ErrMimeTypeMismatch, declared, detected)
+	}
+
+	return policy.check(declared, len(data))
+}
+
+func (p *Policy) check(mimeType string, size int) error {
+	if !p.Allows(mimeType) {
+		return fmt.Errorf("%w: %s", ErrMimeTypeNotAllowed, mimeType)
+	}
+
+	if p.MaxSize > 0 && size > p.MaxSize {
+		return ErrObjectTooLarge
+	}
+
+	return nil
+}
+
+func detectable(mimeType string) bool {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"),
+		strings.HasPrefix(mimeType, "audio/"),
+		strings.HasPrefix(mimeType, "video/"),
+		mimeType == "application/pdf",
+		mimeType == "application/zip",
+		mimeType == "application/x-gzip":
+		return true
+	default:
+		return false
+	}
+}
+
+func baseMimeType(mimeType string) string {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(mimeType))
+	}
+
+	return mediaType
+}
@@ -62,6 +62,33 @@ func New(mime string, data []byte) (*Object, error) {
 	return newObject(object), nil
 }
 
+// FromRemote creates a reference to an object that has already been uploaded, so it
+// can be downloaded using it's id and encryption key
+func FromRemote(id, key []byte, mime string) (*Object, error) {
+	var object *C.self_object
+
+	mimeType := C.CString(mime)
+	idBuf := C.CBytes(id)
+	keyBuf := C.CBytes(key)
+
+	result := C.self_object_create_remote(
+		&object,
+		(*C.uint8_t)(idBuf),
+		(*C.uint8_t)(keyBuf),
+		mimeType,
+	)
+
+	C.free(unsafe.Pointer(mimeType))
+	C.free(idBuf)
+	C.free(keyBuf)
+
+	if result > 0 {
+		return nil, status.New(result)
+	}
+
+	return newObject(object), nil
+}
+
 // Id returns the hash of the encrypted data
 func (o *Object) Id() []byte {
 	return C.GoBytes(
@@ -119,6 +146,13 @@ func (o *Object) Data() []byte {
 	)
 }
 
+// Size returns the size of the objects data
+func (o *Object) Size() int {
+	return int(C.self_object_data_len(
+		o.ptr,
+	))
+}
+
 func fromObjectCollection(collection *C.self_collection_object) []*Object {
 	collectionLen := int(C.self_collection_object_len(
 		collection,
@@ -0,0 +1,153 @@
+package object
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+const (
+	// MimeTypeManifest the mime type of an object that describes a larger object split into chunks
+	MimeTypeManifest = "application/vnd.joinself.object-manifest+json"
+	// MimeTypeChunk the mime type of a single chunk of a larger object
+	MimeTypeChunk = "application/octet-stream"
+	// DefaultChunkSize the default size of chunks a stream is split into
+	DefaultChunkSize = 4 << 20
+)
+
+// ProgressFunc is invoked as an object is transferred, with the number of bytes transferred so far.
+// total will be -1 if the size of the object is not yet known
+type ProgressFunc func(transferred, total int64)
+
+// Manifest describes an object that has been split into encrypted chunks
+type Manifest struct {
+	MimeType  string   `json:"mimeType"`
+	Size      int64    `json:"size"`
+	ChunkSize int      `json:"chunkSize"`
+	Chunks    []*Chunk `json:"chunks"`
+}
+
+// Chunk describes a single encrypted chunk of an object
+type Chunk struct {
+	ID   []byte `json:"id"`
+	Key  []byte `json:"key"`
+	Hash []byte `json:"hash"`
+	Size int    `json:"size"`
+}
+
+// Stream reads an object from a reader, encrypting it one chunk at a time
+type Stream struct {
+	mime       string
+	reader     io.Reader
+	chunkSize  int
+	progress   ProgressFunc
+	transferID string
+}
+
+// NewFromReader creates a new stream that splits the data read from reader into encrypted chunks,
+// so large objects can be uploaded without holding them in memory
+func NewFromReader(mime string, reader io.Reader) *Stream {
+	return &Stream{
+		mime:      mime,
+		reader:    reader,
+		chunkSize: DefaultChunkSize,
+	}
+}
+
+// ChunkSize sets the size of the chunks the stream is split into
+func (s *Stream) ChunkSize(size int) *Stream {
+	if size > 0 {
+		s.chunkSize = size
+	}
+
+	return s
+}
+
+// OnProgress sets a callback that is invoked after each chunk has been transferred
+func (s *Stream) OnProgress(progress ProgressFunc) *Stream {
+	s.progress = progress
+	return s
+}
+
+// Resumable sets an identifier for the transfer, which is used to track chunks that have already
+// been uploaded. If an upload is interrupted, uploading a new stream of the same data with the
+// same identifier will skip any chunks that were previously uploaded
+func (s *Stream) Resumable(transferID string) *Stream {
+	s.transferID = transferID
+	return s
+}
+
+// MimeType returns the mime type of the streamed object
+func (s *Stream) MimeType() string {
+	return s.mime
+}
+
+// TransferID returns the identifier of a resumable transfer, or an empty string if not set
+func (s *Stream) TransferID() string {
+	return s.transferID
+}
+
+// Next reads and encrypts the next chunk from the stream, returning io.EOF once
+// the stream has been read to completion
+func (s *Stream) Next() (*Object, int, error) {
+	buf := make([]byte, s.chunkSize)
+
+	n, err := io.ReadFull(s.reader, buf)
+	if err == io.EOF {
+		return nil, 0, io.EOF
+	}
+
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, 0, err
+	}
+
+	chunk, err := New(MimeTypeChunk, buf[:n])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return chunk, n, nil
+}
+
+// ReportProgress reports the number of bytes of the stream that have been transferred
+func (s *Stream) ReportProgress(transferred int64) {
+	if s.progress != nil {
+		s.progress(transferred, -1)
+	}
+}
+
+// Encode encodes the manifest to json
+func (m *Manifest) Encode() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// Object creates an encrypted object containing the manifest, which can be shared in place of the original object
+func (m *Manifest) Object() (*Object, error) {
+	encoded, err := m.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(MimeTypeManifest, encoded)
+}
+
+// DecodeManifest decodes a manifest from it's json form
+func DecodeManifest(encodedManifest []byte) (*Manifest, error) {
+	var manifest Manifest
+
+	err := json.Unmarshal(encodedManifest, &manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.ChunkSize < 1 {
+		return nil, errors.New("invalid manifest chunk size")
+	}
+
+	return &manifest, nil
+}
+
+// IsManifest returns true if the object describes an object that has been split into chunks
+func IsManifest(o *Object) bool {
+	return o.MimeType() == MimeTypeManifest
+}
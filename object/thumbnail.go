@@ -0,0 +1,183 @@
+package object
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"strings"
+)
+
+var (
+	// ErrNotAnImage returned when attempting to generate a thumbnail for an object that is not an image
+	ErrNotAnImage = errors.New("object is not an image")
+	// ErrUnsupportedImage returned when attempting to generate a thumbnail for an image format that cannot be decoded
+	ErrUnsupportedImage = errors.New("image format not supported")
+	// ErrImageTooLarge returned when attempting to generate a thumbnail for an image with dimensions exceeding MaxThumbnailSourcePixels
+	ErrImageTooLarge = errors.New("image dimensions exceed maximum")
+)
+
+const (
+	// DefaultThumbnailDimension the default maximum width or height of a thumbnail
+	DefaultThumbnailDimension = 256
+	// MaxThumbnailSourcePixels the maximum number of pixels of an image a thumbnail can be generated for.
+	// The dimensions of an image are checked before it is decoded, so small images that declare very
+	// large dimensions are rejected before any memory is allocated for them
+	MaxThumbnailSourcePixels = 64 * 1024 * 1024
+)
+
+// the mime type parameter of a thumbnail that holds the hex encoded hash of the original object
+const thumbnailParameter = "thumbnail"
+
+// Thumbnail generates a scaled down copy of an image object, where the largest
+// dimension of the image does not exceed maxDimension. Images with transparency
+// are encoded as png, all other images are encoded as jpeg. The thumbnail's mime type
+// records the hash of the original object, which is returned by ThumbnailOf.
+// Supported formats are jpeg, png and gif. The object must have been downloaded
+func (o *Object) Thumbnail(maxDimension int) (*Object, error) {
+	if !strings.HasPrefix(baseMimeType(o.MimeType()), "image/") {
+		return nil, ErrNotAnImage
+	}
+
+	if maxDimension < 1 {
+		maxDimension = DefaultThumbnailDimension
+	}
+
+	data := o.Data()
+
+	var decodeConfig func(r io.Reader) (image.Config, error)
+	var decode func(r io.Reader) (image.Image, error)
+
+	switch DetectMimeType(data) {
+	case "image/jpeg":
+		decodeConfig, decode = jpeg.DecodeConfig, jpeg.Decode
+	case "image/png":
+		decodeConfig, decode = png.DecodeConfig, png.Decode
+	case "image/gif":
+		decodeConfig, decode = gif.DecodeConfig, gif.Decode
+	default:
+		if strings.HasPrefix(DetectMimeType(data), "image/") {
+			return nil, ErrUnsupportedImage
+		}
+
+		return nil, ErrNotAnImage
+	}
+
+	config, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Width < 1 || config.Height < 1 || int64(config.Width)*int64(config.Height) > MaxThumbnailSourcePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	src, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnail := scale(src, maxDimension)
+
+	var encoded bytes.Buffer
+
+	mimeType := "image/png"
+
+	if thumbnail.Opaque() {
+		mimeType = "image/jpeg"
+		err = jpeg.Encode(&encoded, thumbnail, &jpeg.Options{Quality: 80})
+	} else {
+		err = png.Encode(&encoded, thumbnail)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return New(mime.FormatMediaType(mimeType, map[string]string{
+		thumbnailParameter: hex.EncodeToString(o.Hash()),
+	}), encoded.Bytes())
+}
+
+// ThumbnailOf returns the hash of the object this object is a thumbnail of.
+// Returns false if the object was not generated by Thumbnail
+func (o *Object) ThumbnailOf() ([]byte, bool) {
+	_, params, err := mime.ParseMediaType(o.MimeType())
+	if err != nil {
+		return nil, false
+	}
+
+	hash, err := hex.DecodeString(params[thumbnailParameter])
+	if err != nil || len(hash) == 0 {
+		return nil, false
+	}
+
+	return hash, true
+}
+
+// scale resizes an image using a box filter, averaging the
+// source pixels that are covered by each destination pixel
+func scale(src image.Image, maxDimension int) *image.NRGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	dstWidth, dstHeight := width, height
+
+	if width > maxDimension || height > maxDimension {
+		if width >= height {
+			dstWidth = maxDimension
+			dstHeight = max(height*maxDimension/width, 1)
+		} else {
+			dstHeight = maxDimension
+			dstWidth = max(width*maxDimension/height, 1)
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := max(bounds.Min.Y+(y+1)*height/dstHeight, y0+1)
+
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := max(bounds.Min.X+(x+1)*width/dstWidth, x0+1)
+
+			var r, g, b, a, n uint64
+
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+
+			// values are alpha premultiplied, so are converted back
+			// to non premultiplied values for the nrgba image
+			var pixel color.NRGBA
+
+			if a > 0 {
+				pixel = color.NRGBA{
+					R: uint8(r * 0xff / a),
+					G: uint8(g * 0xff / a),
+					B: uint8(b * 0xff / a),
+					A: uint8(a / n >> 8),
+				}
+			}
+
+			dst.SetNRGBA(x, y, pixel)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,299 @@
+package revocation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/joinself/self-go-sdk/keypair/signing"
+)
+
+var (
+	// ErrStatementNotCached returned when the statement a proof was created from is not cached
+	ErrStatementNotCached = errors.New("revocation statement not cached")
+	// ErrStatementsStale returned when an issuer's cached statements are older than the cache policy allows
+	ErrStatementsStale = errors.New("revocation statements are stale")
+	// ErrStatementNotSigned returned when adding a statement that has not been signed by it's issuer
+	ErrStatementNotSigned = errors.New("revocation statement not signed by issuer")
+)
+
+// CachePolicy determines how long cached statements can be relied upon
+type CachePolicy struct {
+	// MaxAge the maximum time since an issuer's statements were last synchronized before
+	// they are considered stale. zero allows statements to be used indefinitely
+	MaxAge time.Duration
+	// RequireSync requires an issuer to have been synchronized, or to have cached statements, before
+	// revocations can be checked. Otherwise issuers that have never published a statement are
+	// treated as not having revoked any credentials
+	RequireSync bool
+}
+
+// Cache a verifier side cache of revocation statements, grouped by issuer, that can be
+// used to check revocations without access to the network
+type Cache struct {
+	policy  CachePolicy
+	issuers map[string]*cachedIssuer
+	mu      sync.RWMutex
+}
+
+type cachedIssuer struct {
+	synced     time.Time
+	statements map[uint64]*Statement
+	// contiguous the number of statements cached without a gap in their sequence
+	contiguous uint64
+}
+
+type encodedCache struct {
+	Issuers map[string]*encodedIssuer `json:"issuers"`
+}
+
+type encodedIssuer struct {
+	Synced     time.Time `json:"synced"`
+	Statements [][]byte  `json:"statements"`
+}
+
+// NewCache creates a new revocation statement cache
+func NewCache(policy ...CachePolicy) *Cache {
+	c := &Cache{
+		issuers: make(map[string]*cachedIssuer),
+	}
+
+	if len(policy) > 0 {
+		c.policy = policy[0]
+	}
+
+	return c
+}
+
+// DecodeCache decodes a revocation statement cache. The encoded cache is not signed, so the time issuers
+// were last synchronized is not trusted, and issuers are synchronized as of their latest statement
+func DecodeCache(encodedStatements []byte, policy ...CachePolicy) (*Cache, error) {
+	var encoded encodedCache
+
+	err := json.Unmarshal(encodedStatements, &encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewCache(policy...)
+
+	for _, issuer := range encoded.Issuers {
+		for _, encodedStatement := range issuer.Statements {
+			statement, err := DecodeStatement(encodedStatement)
+			if err != nil {
+				return nil, err
+			}
+
+			err = c.Add(statement)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// Add verifies and adds statements to the cache. Statements must be signed by their issuer. Signatures
+// from statements that have already been cached are merged into the cached statement. An issuer's
+// statements are considered synchronized as of the most recent statement's timestamp
+func (c *Cache) Add(statements ...*Statement) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, statement := range statements {
+		err := statement.Verify()
+		if err != nil {
+			return err
+		}
+
+		if !statement.SignedBy(statement.Issuer()) {
+			return ErrStatementNotSigned
+		}
+
+		address := statement.Issuer().String()
+
+		issuer, ok := c.issuers[address]
+		if !ok {
+			issuer = &cachedIssuer{
+				statements: make(map[uint64]*Statement),
+			}
+			c.issuers[address] = issuer
+		}
+
+		cached, ok := issuer.statements[statement.Sequence()]
+		if ok {
+			if !bytes.Equal(cached.MerkleRoot(), statement.MerkleRoot()) {
+				return fmt.Errorf("conflicting revocation statements for sequence %d", statement.Sequence())
+			}
+
+			err = cached.Merge(statement)
+			if err != nil {
+				return err
+			}
+		} else {
+			issuer.statements[statement.Sequence()] = statement
+		}
+
+		for {
+			_, ok = issuer.statements[issuer.contiguous]
+			if !ok {
+				break
+			}
+
+			issuer.contiguous++
+		}
+
+		if statement.Timestamp().After(issuer.synced) {
+			issuer.synced = statement.Timestamp()
+		}
+	}
+
+	return nil
+}
+
+// Synced marks an issuer's statements as up to date, such as when no new statements have been published
+func (c *Cache) Synced(issuer *signing.PublicKey, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	address := issuer.String()
+
+	cached, ok := c.issuers[address]
+	if !ok {
+		cached = &cachedIssuer{
+			statements: make(map[uint64]*Statement),
+		}
+		c.issuers[address] = cached
+	}
+
+	cached.synced = at
+}
+
+// Statement returns a cached statement by it's issuer and sequence
+func (c *Cache) Statement(issuer *signing.PublicKey, sequence uint64) (*Statement, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cached, ok := c.issuers[issuer.String()]
+	if !ok {
+		return nil, false
+	}
+
+	statement, ok := cached.statements[sequence]
+
+	return statement, ok
+}
+
+// Verify verifies a revocation proof against the cached statement it was created from
+func (c *Cache) Verify(proof *Proof) error {
+	err := c.fresh(proof.Issuer())
+	if err != nil {
+		return err
+	}
+
+	statement, ok := c.Statement(proof.Issuer(), proof.Sequence())
+	if !ok {
+		return ErrStatementNotCached
+	}
+
+	return proof.VerifyAgainst(statement)
+}
+
+// RevokedAt returns the time a revocation hash was revoked by an issuer, such as the revocation hash
+// and signer of a credential. Returns false if none of the issuer's cached statements revoke the hash.
+// Issuers that have never been synchronized are treated as not having revoked the hash, unless the
+// cache policy requires them to be synchronized. Returns ErrStatementNotCached if a statement is
+// missing from the issuer's cached statements
+func (c *Cache) RevokedAt(issuer *signing.PublicKey, revocationHash []byte) (time.Time, bool, error) {
+	err := c.fresh(issuer)
+	if errors.Is(err, ErrStatementNotCached) && !c.policy.RequireSync {
+		return time.Time{}, false, nil
+	}
+
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cached := c.issuers[issuer.String()]
+
+	if uint64(len(cached.statements)) != cached.contiguous {
+		// a missing statement may revoke the hash
+		return time.Time{}, false, fmt.Errorf("%w: missing statement %d", ErrStatementNotCached, cached.contiguous)
+	}
+
+	for _, statement := range cached.statements {
+		revokedAt, ok := statement.RevokedAtBy(revocationHash)
+		if ok {
+			return revokedAt, true, nil
+		}
+	}
+
+	return time.Time{}, false, nil
+}
+
+// Encode encodes the cache, so it can be transferred to devices without network access.
+// Only statements are trusted when the cache is decoded, see DecodeCache
+func (c *Cache) Encode() ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	encoded := encodedCache{
+		Issuers: make(map[string]*encodedIssuer, len(c.issuers)),
+	}
+
+	for address, issuer := range c.issuers {
+		sequences := make([]uint64, 0, len(issuer.statements))
+
+		for sequence := range issuer.statements {
+			sequences = append(sequences, sequence)
+		}
+
+		sort.Slice(sequences, func(i, j int) bool {
+			return sequences[i] < sequences[j]
+		})
+
+		encodedIssuer := &encodedIssuer{
+			Synced:     issuer.synced,
+			Statements: make([][]byte, 0, len(sequences)),
+		}
+
+		for _, sequence := range sequences {
+			encodedStatement, err := issuer.statements[sequence].Encode()
+			if err != nil {
+				return nil, err
+			}
+
+			encodedIssuer.Statements = append(encodedIssuer.Statements, encodedStatement)
+		}
+
+		encoded.Issuers[address] = encodedIssuer
+	}
+
+	return json.Marshal(&encoded)
+}
+
+// fresh checks the issuer's statements have been synchronized within the cache policy's max age.
+// Returns ErrStatementNotCached if the issuer has never been synchronized
+func (c *Cache) fresh(issuer *signing.PublicKey) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cached, ok := c.issuers[issuer.String()]
+	if !ok || (cached.synced.IsZero() && len(cached.statements) == 0) {
+		return ErrStatementNotCached
+	}
+
+	if c.policy.MaxAge > 0 && time.Since(cached.synced) > c.policy.MaxAge {
+		return ErrStatementsStale
+	}
+
+	return nil
+}
@@ -9,13 +9,19 @@ package revocation
 */
 import "C"
 import (
+	"errors"
+	"fmt"
 	"runtime"
 	"time"
 	"unsafe"
 
 	"github.com/joinself/self-go-sdk/keypair/signing"
+	"github.com/joinself/self-go-sdk/status"
 )
 
+// ErrProofMismatch returned when a revocation proof was not created from a statement
+var ErrProofMismatch = errors.New("revocation proof does not match statement")
+
 // Proof a proof that a credential was revoked
 type Proof struct {
 	ptr *C.self_revocation_proof
@@ -86,3 +92,43 @@ func (p *Proof) Signers() []*Signer {
 
 	return signers
 }
+
+// VerifyAgainst verifies the proof was created from the given statement, without requiring
+// access to the network. The statement's signatures are verified, along with the proof's
+// inclusion in the statement's merkle root
+func (p *Proof) VerifyAgainst(statement *Statement) error {
+	if !p.Issuer().Matches(statement.Issuer()) {
+		return fmt.Errorf("%w: issuer", ErrProofMismatch)
+	}
+
+	if p.Sequence() != statement.Sequence() || !p.Timestamp().Equal(statement.Timestamp()) {
+		return fmt.Errorf("%w: sequence", ErrProofMismatch)
+	}
+
+	revokedAt, ok := statement.RevokedAtBy(p.RevocationHash())
+	if !ok || !revokedAt.Equal(p.Revoked()) {
+		return fmt.Errorf("%w: revocation", ErrProofMismatch)
+	}
+
+	for _, signer := range p.Signers() {
+		if !statement.SignedBy(signer.Address()) {
+			return fmt.Errorf("%w: signers", ErrProofMismatch)
+		}
+	}
+
+	err := statement.Verify()
+	if err != nil {
+		return err
+	}
+
+	result := C.self_revocation_proof_verify_against(
+		p.ptr,
+		statement.ptr,
+	)
+
+	if result > 0 {
+		return status.New(result)
+	}
+
+	return nil
+}
@@ -0,0 +1,583 @@
+// Package registry provides an issuer side revocation registry that tracks the revocation
+// hashes of issued credentials and publishes revocations as sequenced statements
+package registry
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	_ "unsafe"
+
+	"github.com/joinself/self-go-sdk/account"
+	"github.com/joinself/self-go-sdk/credential"
+	"github.com/joinself/self-go-sdk/keypair/signing"
+	"github.com/joinself/self-go-sdk/revocation"
+)
+
+//go:linkname accountBackground github.com/joinself/self-go-sdk/account.accountBackground
+func accountBackground(*account.Account, func(done chan struct{})) bool
+
+const keyPrefix = "revocation/registry/"
+
+const (
+	// StateActive the credential has not been revoked
+	StateActive State = "active"
+	// StatePending the credential will be revoked in the next statement
+	StatePending State = "pending"
+	// StateSigning the credential has been revoked by a statement that is awaiting co-signatures or has not been published
+	StateSigning State = "signing"
+	// StateRevoked the credential has been revoked by a published statement
+	StateRevoked State = "revoked"
+)
+
+var (
+	// ErrNotRegistered returned when revoking a credential that has not been registered
+	ErrNotRegistered = errors.New("credential not registered")
+	// ErrStatementNotFound returned when merging a statement the registry is not awaiting signatures for
+	ErrStatementNotFound = errors.New("revocation statement not found")
+)
+
+// State the revocation state of a registered credential
+type State string
+
+// Policy determines how revocations are published
+type Policy struct {
+	// BatchInterval how often pending revocations are published. zero only publishes revocations when Flush is called
+	BatchInterval time.Duration
+	// CoSigners keys that must co-sign each statement before it is published
+	CoSigners []*signing.PublicKey
+	// OnError an optional callback invoked when publishing a batch of revocations fails
+	OnError func(err error)
+}
+
+// Entry the revocation record of a registered credential
+type Entry struct {
+	CredentialHash []byte    `json:"credentialHash"`
+	RevocationHash []byte    `json:"revocationHash"`
+	State          State     `json:"state"`
+	Revoked        time.Time `json:"revoked,omitzero"`
+	Sequence       uint64    `json:"sequence,omitempty"`
+}
+
+// statementRecord a statement that is awaiting co-signatures
+type statementRecord struct {
+	Statement        []byte   `json:"statement"`
+	CredentialHashes [][]byte `json:"credentialHashes"`
+}
+
+// Registry tracks the revocation hashes of credentials issued by an issuer
+// and publishes their revocation in sequenced statements
+type Registry struct {
+	account *account.Account
+	issuer  *signing.PublicKey
+	policy  Policy
+	prefix  string
+	mu      sync.Mutex
+	done    chan struct{}
+	closed  sync.Once
+	workers sync.WaitGroup
+}
+
+// New creates a revocation registry for credentials issued by the issuer's signing key,
+// which must be held by the account. If a batch interval is set by the policy,
+// pending revocations are published periodically until the registry or account is closed
+func New(acc *account.Account, issuer *signing.PublicKey, policy ...Policy) *Registry {
+	r := &Registry{
+		account: acc,
+		issuer:  issuer,
+		prefix:  keyPrefix + issuer.String() + "/",
+		done:    make(chan struct{}),
+	}
+
+	if len(policy) > 0 {
+		r.policy = policy[0]
+	}
+
+	if r.policy.BatchInterval > 0 {
+		r.workers.Add(1)
+
+		// the account waits for the batch to stop before it is closed
+		started := accountBackground(acc, func(done chan struct{}) {
+			defer r.workers.Done()
+			r.batch(r.policy.BatchInterval, done)
+		})
+
+		if !started {
+			r.workers.Done()
+		}
+	}
+
+	return r
+}
+
+// Close stops publishing pending revocations, waiting for any batch that is being published
+func (r *Registry) Close() {
+	r.closed.Do(func() {
+		close(r.done)
+	})
+
+	r.workers.Wait()
+}
+
+// Policy returns the policy used to publish revocations
+func (r *Registry) Policy() Policy {
+	return r.policy
+}
+
+// Register records the revocation hashes of issued credentials. Credentials that have
+// already been registered are skipped, so registering them again never changes their state
+func (r *Registry) Register(credentials ...*credential.VerifiableCredential) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range credentials {
+		credentialHash, err := c.CredentialHash()
+		if err != nil {
+			return err
+		}
+
+		_, err = r.Entry(credentialHash)
+		if err == nil {
+			continue
+		}
+
+		revocationHash, err := c.RevocationHash()
+		if err != nil {
+			return err
+		}
+
+		err = r.entryStore(&Entry{
+			CredentialHash: credentialHash,
+			RevocationHash: revocationHash,
+			State:          StateActive,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Entry returns the revocation record of a registered credential
+func (r *Registry) Entry(credentialHash []byte) (*Entry, error) {
+	encodedEntry, err := r.account.ValueLookup(r.entryKey(credentialHash))
+	if err != nil {
+		return nil, ErrNotRegistered
+	}
+
+	var entry Entry
+
+	return &entry, json.Unmarshal(encodedEntry, &entry)
+}
+
+// Revoke queues a registered credential for revocation, taking effect at the given time.
+// The revocation is published by the next call to Flush
+func (r *Registry) Revoke(credentialHash []byte, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, err := r.Entry(credentialHash)
+	if err != nil {
+		return err
+	}
+
+	if entry.State != StateActive {
+		// the credential has already been revoked
+		return nil
+	}
+
+	entry.State = StatePending
+	entry.Revoked = revokedAt
+
+	return r.entryStore(entry)
+}
+
+// RevokedAt returns the time a credential's revocation took effect.
+// Returns false if the credential's revocation has not been published
+func (r *Registry) RevokedAt(credentialHash []byte) (time.Time, bool, error) {
+	entry, err := r.Entry(credentialHash)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	if entry.State != StateRevoked {
+		return time.Time{}, false, nil
+	}
+
+	return entry.Revoked, true, nil
+}
+
+// Sequence returns the sequence number that will be allocated to the next statement
+func (r *Registry) Sequence() (uint64, error) {
+	encodedSequence, err := r.account.ValueLookup(r.prefix + "sequence")
+	if err != nil || len(encodedSequence) != 8 {
+		// no statements have been issued yet
+		return 0, nil
+	}
+
+	return binary.BigEndian.Uint64(encodedSequence), nil
+}
+
+// Flush creates a signed statement revoking all pending revocations. If the policy has no
+// co-signers the statement is published, otherwise the statement is returned so it can be
+// sent to the co-signers, and is published once their signatures have been merged. Signed
+// statements that previously failed to publish are published again first.
+// Returns nil if there are no pending revocations
+func (r *Registry) Flush() (*revocation.Statement, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.republish()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := r.entries(StatePending)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+
+	sequence, err := r.Sequence()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	builder := revocation.NewStatement().
+		Issuer(r.issuer).
+		Sequence(sequence).
+		Timestamp(now)
+
+	for _, entry := range entries {
+		builder.RevokeBy(entry.RevocationHash, entry.Revoked)
+	}
+
+	// co-signers sign the statement with their own accounts, and
+	// their signatures are added when the statement is merged
+	builder.SignWith(r.issuer, now)
+
+	statement, err := builder.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.account.RevocationSign(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	// the sequence is reserved before the statement is published, so a statement
+	// with the same sequence can never be issued if publishing it succeeds
+	err = r.account.ValueStore(r.prefix+"sequence", binary.BigEndian.AppendUint64(nil, sequence+1))
+	if err != nil {
+		return nil, err
+	}
+
+	// the statement is kept until it is published, so it can be published again if publishing fails
+	err = r.statementStore(statement, entries)
+	if err != nil {
+		// release the reserved sequence, so it does not leave a gap
+		_ = r.account.ValueStore(r.prefix+"sequence", binary.BigEndian.AppendUint64(nil, sequence))
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		entry.State = StateSigning
+		entry.Sequence = sequence
+
+		err = r.entryStore(entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(r.policy.CoSigners) == 0 {
+		err = r.publish(statement)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return statement, nil
+}
+
+// Merge merges a statement co-signed by one of the policy's co-signers into the statement
+// awaiting signatures. Once signed by all co-signers the statement is published.
+// Returns true if the statement was published
+func (r *Registry) Merge(coSigned *revocation.Statement) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !coSigned.Issuer().Matches(r.issuer) {
+		return false, ErrStatementNotFound
+	}
+
+	key := r.statementKey(coSigned.Sequence())
+
+	encodedRecord, err := r.account.ValueLookup(key)
+	if err != nil {
+		return false, ErrStatementNotFound
+	}
+
+	var record statementRecord
+
+	err = json.Unmarshal(encodedRecord, &record)
+	if err != nil {
+		return false, err
+	}
+
+	statement, err := revocation.DecodeStatement(record.Statement)
+	if err != nil {
+		return false, err
+	}
+
+	err = statement.Merge(coSigned)
+	if err != nil {
+		return false, err
+	}
+
+	if !r.signed(statement) {
+		// still awaiting signatures
+		record.Statement, err = statement.Encode()
+		if err != nil {
+			return false, err
+		}
+
+		encodedRecord, err = json.Marshal(&record)
+		if err != nil {
+			return false, err
+		}
+
+		return false, r.account.ValueStore(key, encodedRecord)
+	}
+
+	err = r.account.RevocationRevoke(statement)
+	if err != nil {
+		return false, err
+	}
+
+	return true, r.published(key, &record)
+}
+
+// Awaiting returns the statements that are awaiting co-signatures
+func (r *Registry) Awaiting() ([]*revocation.Statement, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statements, err := r.awaiting()
+	if err != nil {
+		return nil, err
+	}
+
+	var awaiting []*revocation.Statement
+
+	for _, statement := range statements {
+		if !r.signed(statement) {
+			awaiting = append(awaiting, statement)
+		}
+	}
+
+	return awaiting, nil
+}
+
+// awaiting returns the statements held by the registry that have not been published
+func (r *Registry) awaiting() ([]*revocation.Statement, error) {
+	keys, err := r.account.ValueKeys(r.prefix + "statements/")
+	if err != nil {
+		return nil, err
+	}
+
+	statements := make([]*revocation.Statement, 0, len(keys))
+
+	for _, key := range keys {
+		encodedRecord, err := r.account.ValueLookup(key)
+		if err != nil {
+			return nil, err
+		}
+
+		var record statementRecord
+
+		err = json.Unmarshal(encodedRecord, &record)
+		if err != nil {
+			return nil, err
+		}
+
+		statement, err := revocation.DecodeStatement(record.Statement)
+		if err != nil {
+			return nil, err
+		}
+
+		statements = append(statements, statement)
+	}
+
+	return statements, nil
+}
+
+// batch periodically publishes pending revocations until the registry or account is closed
+func (r *Registry) batch(interval time.Duration, accountDone chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-accountDone:
+			return
+		case <-ticker.C:
+			_, err := r.Flush()
+			if err != nil && r.policy.OnError != nil {
+				r.policy.OnError(err)
+			}
+		}
+	}
+}
+
+func (r *Registry) entries(state State) ([]*Entry, error) {
+	keys, err := r.account.ValueKeys(r.prefix + "entries/")
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+
+	for _, key := range keys {
+		credentialHash, err := hex.DecodeString(strings.TrimPrefix(key, r.prefix+"entries/"))
+		if err != nil {
+			continue
+		}
+
+		entry, err := r.Entry(credentialHash)
+		if err != nil {
+			return nil, err
+		}
+
+		if entry.State == state {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func (r *Registry) entryStore(entry *Entry) error {
+	encodedEntry, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return r.account.ValueStore(r.entryKey(entry.CredentialHash), encodedEntry)
+}
+
+// signed returns true if a statement has been signed by all of the policy's co-signers
+func (r *Registry) signed(statement *revocation.Statement) bool {
+	for _, coSigner := range r.policy.CoSigners {
+		if !statement.SignedBy(coSigner) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// publish publishes a statement signed by all co-signers that is held by the registry
+func (r *Registry) publish(statement *revocation.Statement) error {
+	key := r.statementKey(statement.Sequence())
+
+	encodedRecord, err := r.account.ValueLookup(key)
+	if err != nil {
+		return ErrStatementNotFound
+	}
+
+	var record statementRecord
+
+	err = json.Unmarshal(encodedRecord, &record)
+	if err != nil {
+		return err
+	}
+
+	err = r.account.RevocationRevoke(statement)
+	if err != nil {
+		return err
+	}
+
+	return r.published(key, &record)
+}
+
+// published marks the credentials revoked by a published statement as revoked, and removes the statement
+func (r *Registry) published(key string, record *statementRecord) error {
+	for _, credentialHash := range record.CredentialHashes {
+		entry, err := r.Entry(credentialHash)
+		if err != nil {
+			return err
+		}
+
+		entry.State = StateRevoked
+
+		err = r.entryStore(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.account.ValueRemove(key)
+}
+
+// republish publishes held statements that have been signed by all co-signers, but failed to publish
+func (r *Registry) republish() error {
+	statements, err := r.awaiting()
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		if !r.signed(statement) {
+			continue
+		}
+
+		err = r.publish(statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Registry) statementStore(statement *revocation.Statement, entries []*Entry) error {
+	encodedStatement, err := statement.Encode()
+	if err != nil {
+		return err
+	}
+
+	record := statementRecord{
+		Statement: encodedStatement,
+	}
+
+	for _, entry := range entries {
+		record.CredentialHashes = append(record.CredentialHashes, entry.CredentialHash)
+	}
+
+	encodedRecord, err := json.Marshal(&record)
+	if err != nil {
+		return err
+	}
+
+	return r.account.ValueStore(r.statementKey(statement.Sequence()), encodedRecord)
+}
+
+func (r *Registry) entryKey(credentialHash []byte) string {
+	return r.prefix + "entries/" + hex.EncodeToString(credentialHash)
+}
+
+func (r *Registry) statementKey(sequence uint64) string {
+	return fmt.Sprintf("%sstatements/%020d", r.prefix, sequence)
+}
@@ -240,6 +240,19 @@ func (s *Statement) Revocations() []*Revocation {
 	return revocations
 }
 
+// Verify verifies the signatures of the statement's signers
+func (s *Statement) Verify() error {
+	result := C.self_revocation_statement_verify(
+		s.ptr,
+	)
+
+	if result > 0 {
+		return status.New(result)
+	}
+
+	return nil
+}
+
 // Merge merges the signatures from another statement into this one
 func (s *Statement) Merge(other *Statement) error {
 	result := C.self_revocation_statement_merge(